@@ -0,0 +1,329 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"marketplace-service/db"
+	"marketplace-service/internal/config"
+	"marketplace-service/internal/database"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/register"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"marketplace-service/internal/validation"
+
+	"github.com/urfave/cli/v2"
+)
+
+func main() {
+	l := logger.GetLogger()
+
+	app := &cli.App{
+		Name:  "marketplace-service",
+		Usage: "marketplace backend and the commands to operate it",
+		Action: func(c *cli.Context) error {
+			serve(l, config.GetConfig(l))
+			return nil
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "serve",
+				Usage: "run the HTTP server, the default command",
+				Action: func(c *cli.Context) error {
+					serve(l, config.GetConfig(l))
+					return nil
+				},
+			},
+			{
+				Name:   "migrate",
+				Usage:  "create the tables and the columns missing in the database",
+				Action: withDB(l, migrate),
+			},
+			{
+				Name:  "users",
+				Usage: "manage the users",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "list the users in the order of registration",
+						Flags: []cli.Flag{
+							&cli.IntFlag{Name: "page", Value: 1},
+							&cli.IntFlag{Name: "limit", Value: 50},
+						},
+						Action: withDB(l, listUsers),
+					},
+					{
+						Name:  "create-admin",
+						Usage: "create an administrator",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "username", Required: true},
+							&cli.StringFlag{Name: "password", Required: true, EnvVars: []string{"ADMIN_PASSWORD"}},
+						},
+						Action: withDB(l, createAdmin),
+					},
+					{
+						Name:      "reset-password",
+						Usage:     "set a new password of the user",
+						ArgsUsage: "USERNAME",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "password", Required: true, EnvVars: []string{"NEW_PASSWORD"}},
+						},
+						Action: withDB(l, resetPassword),
+					},
+					{
+						Name:      "ban",
+						Usage:     "forbid the user to log in",
+						ArgsUsage: "USERNAME",
+						Action:    withDB(l, setBanned(true)),
+					},
+					{
+						Name:      "unban",
+						Usage:     "allow the banned user to log in again",
+						ArgsUsage: "USERNAME",
+						Action:    withDB(l, setBanned(false)),
+					},
+					{
+						Name:      "token",
+						Usage:     "print a token of the user for debugging",
+						ArgsUsage: "USERNAME",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{Name: "ttl", Value: time.Hour},
+						},
+						Action: withDB(l, issueToken),
+					},
+				},
+			},
+			{
+				Name:  "announcements",
+				Usage: "manage the announcements",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "archive",
+						Usage:     "archive an active or reserved announcement",
+						ArgsUsage: "ID",
+						Action:    withDB(l, archiveAnnouncement),
+					},
+					{
+						Name:      "restore",
+						Usage:     "return an archived announcement to the feed for ANNOUNCEMENT_TTL",
+						ArgsUsage: "ID",
+						Action:    withDB(l, restoreAnnouncement),
+					},
+				},
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		l.Fatal(err)
+	}
+}
+
+// env is what the commands work with: the same config and stores the server
+// uses.
+type env struct {
+	logger        logger.Logger
+	cfg           *config.Config
+	db            *sql.DB
+	users         store.UserStore
+	announcements store.AnnouncementsStore
+	expiration    store.ExpirationStore
+}
+
+// command is an action of the CLI that works with the database.
+type command func(c *cli.Context, e *env) error
+
+// withDB connects to the database of the config for the command and closes
+// the connection when it is done.
+func withDB(l logger.Logger, cmd command) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		cfg := config.GetConfig(l)
+		db, err := database.ConnectToDatabase(cfg, l)
+		if err != nil {
+			return err
+		}
+		defer database.CloseConnection(db)
+
+		announcementStore := store.NewPostgresAnnouncementsStore(db)
+		return cmd(c, &env{
+			logger:        l,
+			cfg:           cfg,
+			db:            db,
+			users:         store.NewPostgresUserStore(db),
+			announcements: announcementStore,
+			expiration:    announcementStore,
+		})
+	}
+}
+
+// feedChanged tells the running services to drop the cached pages of the
+// public list. The change itself is already made, so a failure is only logged.
+func (e *env) feedChanged() {
+	if err := store.NotifyFeedChanged(e.db); err != nil {
+		e.logger.Warn("The cached feed pages were not dropped: ", err)
+	}
+}
+
+func migrate(c *cli.Context, e *env) error {
+	if err := database.Migrate(e.db, db.Schema); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.App.Writer, "The database is up to date")
+	return nil
+}
+
+func listUsers(c *cli.Context, e *env) error {
+	if c.Int("page") < 1 || c.Int("limit") < 1 {
+		return errors.New("page and limit must be positive")
+	}
+
+	users, err := e.users.ListUsers(c.Int("page"), c.Int("limit"))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tDISPLAY NAME\tADMIN\tBANNED AT\tREGISTERED AT")
+	for _, user := range users {
+		bannedAt := "-"
+		if user.BannedAt != nil {
+			bannedAt = user.BannedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%s\t%s\n",
+			user.ID, user.Username, user.DisplayName, user.IsAdmin, bannedAt, user.Date.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// createAdmin checks the credentials by the rules of the registration.
+func createAdmin(c *cli.Context, e *env) error {
+	credentials := register.RegisterRequest{Username: c.String("username"), Password: c.String("password")}
+	if err := validation.Struct(credentials); err != nil {
+		return err
+	}
+
+	user := &model.User{Username: credentials.Username, Password: credentials.Password, IsAdmin: true}
+	id, err := e.users.CreateUser(user)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.App.Writer, "Created the administrator %s with id %d\n", user.Username, id)
+	return nil
+}
+
+// resetPassword checks only the new password, so that it works for the users
+// whose names predate the rules of the registration.
+func resetPassword(c *cli.Context, e *env) error {
+	username, err := usernameArg(c)
+	if err != nil {
+		return err
+	}
+
+	request := struct {
+		Password string `json:"password" validate:"required,min=8,max=64,password"`
+	}{Password: c.String("password")}
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := e.users.UpdatePassword(username, request.Password); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.App.Writer, "The password of %s is reset\n", username)
+	return nil
+}
+
+// setBanned bans or unbans the user. The server checks the ban on every
+// request, so the tokens the user already has stop working at once.
+func setBanned(banned bool) command {
+	return func(c *cli.Context, e *env) error {
+		username, err := usernameArg(c)
+		if err != nil {
+			return err
+		}
+
+		if err := e.users.SetBanned(username, banned); err != nil {
+			return err
+		}
+		if banned {
+			fmt.Fprintf(c.App.Writer, "%s is banned\n", username)
+		} else {
+			fmt.Fprintf(c.App.Writer, "%s is unbanned\n", username)
+		}
+		return nil
+	}
+}
+
+// issueToken prints the bare token, so it can be put in a variable.
+func issueToken(c *cli.Context, e *env) error {
+	username, err := usernameArg(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := e.users.GetUserIdByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	tokenString, err := token.NewService(e.cfg.Secret, c.Duration("ttl"), e.logger).GenerateToken(strconv.FormatInt(id, 10))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.App.Writer, tokenString)
+	return nil
+}
+
+func archiveAnnouncement(c *cli.Context, e *env) error {
+	id, err := idArg(c)
+	if err != nil {
+		return err
+	}
+
+	if err := e.expiration.ArchiveAnnouncement(id); err != nil {
+		return err
+	}
+	e.feedChanged()
+	fmt.Fprintf(c.App.Writer, "The announcement %d is archived\n", id)
+	return nil
+}
+
+func restoreAnnouncement(c *cli.Context, e *env) error {
+	id, err := idArg(c)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(e.cfg.Announcements.TTL)
+	if err := e.announcements.RenewAnnouncement(id, expiresAt); err != nil {
+		return err
+	}
+	e.feedChanged()
+	fmt.Fprintf(c.App.Writer, "The announcement %d is active until %s\n", id, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+func usernameArg(c *cli.Context) (string, error) {
+	if c.NArg() != 1 {
+		return "", errors.New("expected the username")
+	}
+	return c.Args().First(), nil
+}
+
+func idArg(c *cli.Context) (int64, error) {
+	if c.NArg() != 1 {
+		return 0, errors.New("expected the id of the announcement")
+	}
+	id, err := strconv.ParseInt(c.Args().First(), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid id %q", c.Args().First())
+	}
+	return id, nil
+}
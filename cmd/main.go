@@ -12,28 +12,37 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"marketplace-service/internal/announcements"
 	"marketplace-service/internal/auth"
+	"marketplace-service/internal/categories"
+	"marketplace-service/internal/chat"
 	"marketplace-service/internal/config"
+	"marketplace-service/internal/conversations"
 	"marketplace-service/internal/database"
 	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/offers"
+	"marketplace-service/internal/openapi"
 	"marketplace-service/internal/register"
+	"marketplace-service/internal/reviews"
 	"marketplace-service/internal/store"
 	"marketplace-service/internal/token"
+	"marketplace-service/internal/users"
 
 	docs "marketplace-service/docs"
 
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
-
-func main() {
-	l := logger.GetLogger()
-	cfg := config.GetConfig(l)
+// serve runs the HTTP server with the background jobs of the announcements.
+func serve(l logger.Logger, cfg *config.Config) {
 	db, err := database.ConnectToDatabase(cfg, l)
 
 	if err != nil {
@@ -48,24 +57,162 @@ func main() {
 	mux.Handle("/api/v1/swagger/", httpSwagger.Handler(
 		httpSwagger.URL("doc.json"),
 	))
+	mux.HandleFunc("GET /api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(docs.OpenAPI)
+	})
 	
 
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		l.Fatal(err)
+	}
+
 	userStore := store.NewPostgresUserStore(db)
+	token.RejectBanned(func(userID string) (bool, error) {
+		id, err := strconv.ParseInt(userID, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		return userStore.IsBanned(id)
+	})
 
 	regHandler := register.NewHandler(userStore, l, token)
 	regHandler.RegisterRoutes(mux)
 
-	authHandler := auth.NewHandler(userStore, l, token)
+	var loginAttempts store.LoginAttemptsStore
+	if cfg.Login.Store == "postgres" {
+		loginAttempts = store.NewPostgresLoginAttemptsStore(db)
+	} else {
+		memoryAttempts := store.NewMemoryLoginAttemptsStore()
+		go store.RunCleanup(context.Background(), memoryAttempts, cfg.Login.CleanupInterval, l)
+		loginAttempts = memoryAttempts
+	}
+
+	lockout := auth.NewLockout(
+		loginAttempts,
+		auth.LockoutPolicy{
+			MaxFailures: cfg.Login.MaxFailures,
+			Window:      cfg.Login.FailureWindow,
+			Lockout:     cfg.Login.Lockout,
+			MaxLockout:  cfg.Login.MaxLockout,
+		},
+		auth.LockoutPolicy{
+			MaxFailures: cfg.Login.IPMaxFailures,
+			Window:      cfg.Login.FailureWindow,
+			Lockout:     cfg.Login.Lockout,
+			MaxLockout:  cfg.Login.MaxLockout,
+		},
+	)
+
+	authHandler := auth.NewHandler(userStore, lockout, l, token)
 	authHandler.RegisterService(mux)
 
+	usersHandler := users.NewHandler(userStore, l, token)
+	usersHandler.RegisterService(mux)
+
+	notificationsStore := store.NewPostgresNotificationsStore(db)
+	notificationsBroker := notifications.NewBroker()
+
+	notificationsHandler := notifications.NewHandler(notificationsStore, notificationsBroker, l, token, cfg.Notifications.Heartbeat, cfg.Paging.MaxLimit)
+	notificationsHandler.RegisterService(mux)
+
+	notifier := notifications.NewService(notificationsStore, notificationsBroker)
+
+	categoriesStore := store.NewPostgresCategoriesStore(db)
+
+	categoriesHandler := categories.NewHandler(categoriesStore, l)
+	categoriesHandler.RegisterService(mux)
+
 	announcementStore := store.NewPostgresAnnouncementsStore(db)
 
-	announcementsHandler := announcements.NewHandler(announcementStore, l, token)
+	var feedStore store.AnnouncementsStore = announcementStore
+	var feed store.FeedInvalidator = store.NoFeedCache{}
+	if cfg.FeedCache.Backend == "memory" {
+		cachedStore := store.NewCachedAnnouncementsStore(announcementStore, store.NewMemoryResponseCache(cfg.FeedCache.Size), l, cfg.FeedCache.TTL)
+		if _, err := store.ListenFeedChanges(database.ConnectionInfo(cfg), cachedStore, l); err != nil {
+			l.Fatal(err)
+		}
+		feedStore, feed = cachedStore, cachedStore
+	}
+
+	announcementsHandler := announcements.NewHandler(feedStore, announcementStore, categoriesStore, notifier, l, token, cfg.Announcements.TTL, cfg.Announcements.FeedMaxAge, cfg.Paging.MaxLimit)
 	announcementsHandler.RegisterService(mux)
 
+	sweeper := announcements.NewSweeper(announcementStore, feed, notifier, l, cfg.Announcements.SweepInterval, cfg.Announcements.ExpiryWarning)
+	go sweeper.Run(context.Background())
+
+	scheduler := announcements.NewScheduler(announcementStore, feed, categoriesStore, notifier, l, cfg.Announcements.PublishInterval, cfg.Announcements.TTL)
+	go scheduler.Run(context.Background())
+
+	var chatHub chat.Hub = chat.NewLocalHub()
+	if cfg.Chat.Hub == "postgres" {
+		chatHub, err = chat.NewPostgresHub(db, database.ConnectionInfo(cfg), l)
+		if err != nil {
+			l.Fatal(err)
+		}
+	}
+
+	conversationsStore := store.NewPostgresConversationsStore(db)
+
+	conversationsHandler := conversations.NewHandler(conversationsStore, chatHub, notifier, l, token, cfg.Paging.MaxLimit)
+	conversationsHandler.RegisterService(mux)
+
+	chatHandler := chat.NewHandler(conversationsStore, chatHub, l, token)
+	chatHandler.RegisterService(mux)
+
+	reviewsStore := store.NewPostgresReviewsStore(db)
+
+	reviewsHandler := reviews.NewHandler(reviewsStore, userStore, feed, notifier, l, token, cfg.Reviews.EditWindow, cfg.Paging.MaxLimit)
+	reviewsHandler.RegisterService(mux)
+
+	offersStore := store.NewPostgresOffersStore(db)
+
+	offersHandler := offers.NewHandler(offersStore, announcementStore, feed, notifier, l, token, cfg.Offers.TTL)
+	offersHandler.RegisterService(mux)
+
+	rateLimitPolicies, err := middleware.ParseRateLimitPolicies(cfg.RateLimit.Policies)
+	if err != nil {
+		l.Fatal(err)
+	}
+
+	var rateLimitStore store.RateLimitStore = store.NewMemoryRateLimitStore()
+	if cfg.RateLimit.Store == "postgres" {
+		postgresBuckets := store.NewPostgresRateLimitStore(db)
+		go store.RunCleanup(context.Background(), postgresBuckets, cfg.RateLimit.CleanupInterval, l)
+		rateLimitStore = postgresBuckets
+	}
+
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore, token, l, rateLimitPolicies)
+
+	idempotencyStore := store.NewPostgresIdempotencyStore(db)
+	idempotency := middleware.NewIdempotency(idempotencyStore, token, l, cfg.Idempotency.TTL, cfg.Idempotency.LockTimeout, cfg.Idempotency.Wait)
+
+	middlewares := []func(http.Handler) http.Handler{rateLimiter.Routes(mux)}
+	if cfg.OpenAPI.Validation != "off" {
+		doc, err := openapi.Load(docs.OpenAPI)
+		if err != nil {
+			l.Fatal(err)
+		}
+		contract, err := middleware.NewContract(doc, l, cfg.OpenAPI.Validation == "responses")
+		if err != nil {
+			l.Fatal(err)
+		}
+		middlewares = append(middlewares, contract.Middleware)
+	}
+
+	middlewares = append(middlewares, idempotency.Routes(
+		mux,
+		"POST /api/v1/announcements",
+		"POST /api/v1/announcements/{id}/conversations",
+		"POST /api/v1/announcements/{id}/offers",
+		"POST /api/v1/conversations/{id}/messages",
+		"POST /api/v1/users/{username}/reviews",
+		"POST /api/v1/reviews/{id}/reply",
+	))
+
 	server := &http.Server {
 		Addr: fmt.Sprintf("%s:%d", cfg.Listen.BindIp, cfg.Listen.Port),
-		Handler: mux,
+		Handler: middleware.Chain(mux, middlewares...),
 	}
 
 	l.Info("Server is listening on port:", cfg.Listen.Port)
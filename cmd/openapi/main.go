@@ -0,0 +1,37 @@
+// Command openapi writes the OpenAPI 3.1 document of the service made of the
+// Swagger 2.0 description generated by swag:
+//
+//	swag init -g cmd/main.go -o docs && go run ./cmd/openapi
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"marketplace-service/internal/openapi"
+)
+
+func main() {
+	in := flag.String("in", "docs/swagger.json", "Swagger 2.0 description generated by swag")
+	out := flag.String("out", "docs/openapi.json", "OpenAPI 3.1 document to write")
+	flag.Parse()
+
+	swagger, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	doc, err := openapi.Convert(swagger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := openapi.Marshal(doc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
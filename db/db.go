@@ -0,0 +1,8 @@
+// Package db holds the schema of the database. Postgres runs it when the
+// volume is created, and the migrate command runs it on existing databases.
+package db
+
+import _ "embed"
+
+//go:embed init/init.sql
+var Schema string
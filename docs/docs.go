@@ -22,7 +22,7 @@ const docTemplate = `{
                         "Bearer": []
                     }
                 ],
-                "description": "Get a paginated list of announcements. This endpoint is public.",
+                "description": "Get a paginated list of announcements. Expired announcements are not shown. This endpoint is public.\nResponses carry a weak ETag and Last-Modified of the page; a request with a matching If-None-Match gets 304. Anonymous pages may be cached publicly for a short time, pages of authenticated users are private.",
                 "produces": [
                     "application/json"
                 ],
@@ -32,40 +32,119 @@ const docTemplate = `{
                 "summary": "Get announcements list",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Page number for pagination (starts from 1). Defaults to 1.",
-                        "name": "page",
+                        "maxLength": 64,
+                        "type": "string",
+                        "description": "Slug of the category. Required for the attr.* filters",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 100,
+                        "type": "string",
+                        "description": "Show only announcements of the city, case-insensitively. Cannot be combined with radius_km",
+                        "name": "city",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 90,
+                        "minimum": -90,
+                        "type": "number",
+                        "description": "Latitude of the buyer. Together with lon adds distance_km to every announcement",
+                        "name": "lat",
                         "in": "query"
                     },
                     {
+                        "minimum": 1,
                         "type": "integer",
-                        "description": "Number of items per page. Defaults to 10.",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
                         "name": "limit",
                         "in": "query"
                     },
                     {
-                        "enum": [
-                            "price_asc",
-                            "price_desc",
-                            "date_asc",
-                            "date_desc"
-                        ],
-                        "type": "string",
-                        "description": "Sort order for announcements.",
-                        "name": "sort_by",
+                        "maximum": 180,
+                        "minimum": -180,
+                        "type": "number",
+                        "description": "Longitude of the buyer. Set together with lat",
+                        "name": "lon",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2147483647,
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 2147483647,
+                        "description": "Highest price, inclusive",
+                        "name": "max_price",
                         "in": "query"
                     },
                     {
+                        "maximum": 2147483647,
+                        "minimum": 0,
                         "type": "integer",
-                        "description": "All announcements with price more than min_price. Default is 0",
+                        "default": 0,
+                        "description": "Lowest price, inclusive. Must not be greater than max_price",
                         "name": "min_price",
                         "in": "query"
                     },
                     {
+                        "maximum": 100000,
+                        "minimum": 1,
                         "type": "integer",
-                        "description": "All announcements with price less than max_price. Default is (1 \u003c\u003c 31) - 1",
-                        "name": "max_price",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 20000,
+                        "minimum": 0.1,
+                        "type": "number",
+                        "description": "Show only announcements within the radius around lat and lon, which it requires",
+                        "name": "radius_km",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "price_asc",
+                                "price_desc",
+                                "date_asc",
+                                "date_desc",
+                                "price_drop",
+                                "distance"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Sort orders, the first is the most significant. price_drop puts the biggest last price reductions first, distance the nearest to lat and lon, which it requires. Defaults to date_desc",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "reserved",
+                            "all"
+                        ],
+                        "type": "string",
+                        "default": "active",
+                        "description": "Show active, reserved or both kinds of announcements",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by an attribute of the category: attr.X for any attribute, attr.X_min and attr.X_max for int attributes",
+                        "name": "attr.name",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached page",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -76,13 +155,36 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/announcements.AnnouncementsGetResponse"
                             }
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public with max-age for anonymous users, private and no-cache otherwise"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Weak entity tag of the page"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "Time of the latest change among the announcements of the page"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "The cached page is still valid"
+                    },
                     "400": {
-                        "description": "Invalid page or limit parameter"
+                        "description": "Invalid query parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "500": {
-                        "description": "Internal server error"
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             },
@@ -92,7 +194,7 @@ const docTemplate = `{
                         "Bearer": []
                     }
                 ],
-                "description": "Create an announcement for authorized users.",
+                "description": "Create an announcement for authorized users. The announcement is archived when its time to live is over unless the owner renews it.\nWith draft set the announcement is saved as a draft: the fields are checked only for their length and the full rules apply when it is published. A draft with publish_at is published automatically at that time.",
                 "consumes": [
                     "application/json"
                 ],
@@ -112,6 +214,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/announcements.AnnouncementsPostRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeating the request with the same key returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -122,17 +230,230 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request payload"
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "500": {
-                        "description": "Internal server error"
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/auth": {
-            "post": {
-                "description": "Auth user by username and password.",
+        "/api/v1/announcements/drafts": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a paginated list of the drafts of the authorized user, recently changed first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Get own drafts",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/announcements.AnnouncementsGetResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or limit parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/announcements/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a single announcement by id. This endpoint is public. The ETag header holds the version of the announcement to send in If-Match when changing it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Get an announcement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/announcements.AnnouncementsGetResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the announcement"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid announcement id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete an own announcement. The If-Match header must hold the current ETag of the announcement.",
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Delete an announcement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the announcement version being deleted",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Announcement successfully deleted"
+                    },
+                    "400": {
+                        "description": "Invalid announcement id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner of the announcement",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The announcement has been changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Change the fields of an own announcement. The If-Match header must hold the current ETag of the announcement. Omitted fields are left as they are. Price changes are kept in the price history and reported to the users watching the price. publish_at can be changed only for drafts. Changes of drafts are checked only for the length of the fields, like new drafts.\nAttributes replace the previous ones and are checked against the category; when only the category changes the previous attributes are checked against the new one.",
                 "consumes": [
                     "application/json"
                 ],
@@ -140,36 +461,106 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Announcements"
                 ],
-                "summary": "Auth user",
+                "summary": "Edit an announcement",
                 "parameters": [
                     {
-                        "description": "User authorization details",
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the announcement version being changed",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.AuthRequest"
+                            "$ref": "#/definitions/announcements.AnnouncementsPatchRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "User successfully authorized"
+                    "200": {
+                        "description": "Announcement successfully updated",
+                        "schema": {
+                            "$ref": "#/definitions/announcements.AnnouncementsGetResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the announcement"
+                            }
+                        }
                     },
                     "400": {
-                        "description": "Invalid request payload or invalid username or invalid password"
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner of the announcement",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The announcement has been changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "500": {
-                        "description": "Internal server error"
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/users": {
+        "/api/v1/announcements/{id}/conversations": {
             "post": {
-                "description": "Registers a new user with a username and password.",
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Start a conversation with the owner of the announcement, or continue the existing one, with a message.",
                 "consumes": [
                     "application/json"
                 ],
@@ -177,41 +568,2135 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Conversations"
                 ],
-                "summary": "Register a new user",
+                "summary": "Contact the owner of an announcement",
                 "parameters": [
                     {
-                        "description": "User registration details",
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "First message",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/register.RegisterRequest"
+                            "$ref": "#/definitions/conversations.MessagePostRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeating the request with the same key returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "User successfully registered",
+                        "description": "Message sent",
                         "schema": {
-                            "$ref": "#/definitions/register.RegisterResponse"
+                            "$ref": "#/definitions/conversations.ConversationPostResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request payload or user already exists"
+                        "description": "Invalid request payload or own announcement",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
-                    "500": {
-                        "description": "Internal server error"
-                    }
-                }
-            }
-        }
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Messaging between these users is blocked",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/announcements/{id}/offers": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The owner gets all offers on the announcement, other users get only their own ones.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Offers"
+                ],
+                "summary": "Get offers on an announcement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Offer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid announcement id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Offer the owner of an active announcement a price not higher than the asked one. A buyer can have one open offer per announcement.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Offers"
+                ],
+                "summary": "Make an offer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Offered price",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/offers.OfferPostRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeating the request with the same key returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Offer successfully created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Offer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload, amount above the price or own announcement",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Announcement is not active or there is an open offer already",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/announcements/{id}/price-alerts": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a notification every time the price of the announcement goes down.",
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Watch the price",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Subscribed"
+                    },
+                    "400": {
+                        "description": "Invalid announcement id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Stop getting notifications about price drops of the announcement.",
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Stop watching the price",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Unsubscribed"
+                    },
+                    "400": {
+                        "description": "Invalid announcement id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/announcements/{id}/publish": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Publish an own draft right away. The draft must pass the same validation as a new announcement.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Publish a draft",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Draft successfully published",
+                        "schema": {
+                            "$ref": "#/definitions/announcements.AnnouncementsGetResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The draft does not pass the validation",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner of the announcement",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The announcement is not a draft",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/announcements/{id}/renew": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Extend an own announcement for one more time to live starting from now. Archived announcements come back to the feed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Renew an announcement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Announcement id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Announcement successfully renewed",
+                        "schema": {
+                            "$ref": "#/definitions/announcements.AnnouncementsGetResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid announcement id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner of the announcement",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Announcement not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Drafts are published, not renewed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth": {
+            "post": {
+                "description": "Auth user by username and password. Too many failed attempts for the username or from the address lock the login for a while, and the lock grows with every next failure.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Auth user",
+                "parameters": [
+                    {
+                        "description": "User authorization details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.AuthRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User successfully authorized",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Authorization": {
+                                "type": "string",
+                                "description": "Bearer token of the user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or invalid username or invalid password",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "User is banned",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts, see the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/categories": {
+            "get": {
+                "description": "Get all categories with the attributes their announcements have. The attributes are used when creating announcements and as attr.* filters of the announcement list. This endpoint is public.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Categories"
+                ],
+                "summary": "Get categories",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Category"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/chat": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "WebSocket delivering new messages, typing indicators and read receipts of all conversations of the authorized user.\nBrowsers can not set headers on WebSocket requests, so the token may also be passed in the access_token query parameter.\nAfter a reconnect pass the id of the last received message in last_message_id to get the missed messages first.\nClients may send {\"type\": \"typing\" | \"read\", \"conversation_id\": 1}. New messages are sent with POST /api/v1/conversations/{id}/messages.",
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Real-time chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT token, alternative to the Authorization header",
+                        "name": "access_token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of the last received message",
+                        "name": "last_message_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching protocols"
+                    },
+                    "400": {
+                        "description": "Invalid last_message_id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/conversations": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a paginated list of conversations the authorized user takes part in, most recently active first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Get conversations list",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Conversation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or limit parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/conversations/inbox": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get conversations about the announcements of the authorized user grouped by announcement.\nThe page and limit count announcements, each one comes with all of its conversations.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Get seller inbox",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ListingInbox"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or limit parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/conversations/unread": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the number of unread messages in all conversations of the authorized user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Get unread messages counter",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/conversations.UnreadCountResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/conversations/{id}/block": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Block the other participant of the conversation. Messages between blocked users are rejected in both directions.",
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Block the other participant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Conversation id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "User blocked"
+                    },
+                    "400": {
+                        "description": "Invalid conversation id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Remove the block the authorized user has put on the other participant of the conversation.",
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Unblock the other participant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Conversation id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "User unblocked"
+                    },
+                    "400": {
+                        "description": "Invalid conversation id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/conversations/{id}/messages": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a paginated list of messages of the conversation, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Get messages",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Conversation id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Message"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid conversation id, page or limit parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Send a message to the other participant of the conversation.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Send a message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Conversation id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Message",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/conversations.MessagePostRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeating the request with the same key returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Message sent",
+                        "schema": {
+                            "$ref": "#/definitions/model.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Messaging between these users is blocked",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/conversations/{id}/read": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Mark all messages received by the authorized user in the conversation as read.",
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Mark conversation as read",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Conversation id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Conversation marked as read"
+                    },
+                    "400": {
+                        "description": "Invalid conversation id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a paginated list of notifications of the authorized user, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "Get notifications list",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "all",
+                            "read",
+                            "unread"
+                        ],
+                        "type": "string",
+                        "default": "all",
+                        "description": "Filter by read status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Notification"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications/read": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Mark every unread notification of the authorized user as read.",
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "Mark all notifications as read",
+                "responses": {
+                    "204": {
+                        "description": "Notifications marked as read"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications/stream": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Server-Sent Events stream of new notifications of the authorized user.\nBrowsers' EventSource can not send headers, so the token may also be passed in the access_token query parameter.\nSend Last-Event-ID to receive the notifications missed since that id. A comment line is sent as a heartbeat.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "Stream notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT token, alternative to the Authorization header",
+                        "name": "access_token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of the last received notification",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of notification events",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Last-Event-ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications/{id}/read": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Mark a single notification of the authorized user as read.",
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "Mark a notification as read",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Notification id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Notification marked as read"
+                    },
+                    "400": {
+                        "description": "Invalid notification id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Notification not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/offers/{id}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The owner accepts a pending offer, or the buyer accepts the counter offer of the owner. The announcement becomes reserved and the other open offers on it are declined.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Offers"
+                ],
+                "summary": "Accept an offer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Offer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Offer accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.Offer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid offer id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "It is not the user's turn to answer the offer",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Offer not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Offer is no longer open or announcement is not active",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/offers/{id}/counter": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The owner answers a pending offer with another price, between the offered and the asked one. The buyer may then accept or decline it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Offers"
+                ],
+                "summary": "Counter an offer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Offer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Counter price",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/offers.OfferPostRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Offer countered",
+                        "schema": {
+                            "$ref": "#/definitions/model.Offer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or amount out of range",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Only the owner can counter an offer",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Offer not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Offer is not pending",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/offers/{id}/decline": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The owner declines a pending offer, or the buyer declines the counter offer of the owner.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Offers"
+                ],
+                "summary": "Decline an offer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Offer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Offer declined",
+                        "schema": {
+                            "$ref": "#/definitions/model.Offer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid offer id",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "It is not the user's turn to answer the offer",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Offer not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Offer is no longer open",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/reviews/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Change the rating and the text of an own review. It is possible only for a limited time after the review was created.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Edit a review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Review",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/reviews.ReviewPostRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Review successfully updated",
+                        "schema": {
+                            "$ref": "#/definitions/model.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the author of the review or the edit window is over",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/reviews/{id}/reply": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The seller may reply once to each review left for them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Reply to a review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reply",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/reviews.ReplyPostRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeating the request with the same key returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Reply successfully created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "The review is not about the authorized user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Review already has a reply",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users": {
+            "post": {
+                "description": "Registers a new user with a username and password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User registration details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/register.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User successfully registered",
+                        "schema": {
+                            "$ref": "#/definitions/register.RegisterResponse"
+                        },
+                        "headers": {
+                            "Authorization": {
+                                "type": "string",
+                                "description": "Bearer token of the new user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or user already exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/me": {
+            "patch": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Change the display name and avatar of the authorized user. Omitted fields are left as they are, empty strings clear them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Edit own profile",
+                "parameters": [
+                    {
+                        "description": "Profile fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.ProfilePatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated profile",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{username}": {
+            "get": {
+                "description": "Get the public profile of a user: display name, avatar, registration date, number of active announcements and rating.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserProfile"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{username}/announcements": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a paginated list of announcements published by the user, newest first. This endpoint is public.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Announcements"
+                ],
+                "summary": "Get announcements of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username of the owner",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/announcements.AnnouncementsGetResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or limit parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{username}/reviews": {
+            "get": {
+                "description": "Get a paginated list of reviews left for the user, newest first. This endpoint is public.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Get seller reviews",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username of the seller",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page, at most MAX_PAGE_LIMIT (100 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination, starts from 1, at most 100000",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Review"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or limit parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Leave a rating and a review for the seller. Only buyers who have had a conversation about one of the seller's announcements may do it, once per seller.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Review a seller",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username of the seller",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Review",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/reviews.ReviewPostRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeating the request with the same key returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Review successfully created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or own profile",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "No conversation with the seller",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Review already exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
     },
     "definitions": {
         "announcements.AnnouncementsGetResponse": {
             "type": "object",
             "properties": {
+                "attributes": {
+                    "type": "object",
+                    "x-nullable": true
+                },
+                "category": {
+                    "type": "string",
+                    "example": "cars"
+                },
+                "city": {
+                    "type": "string",
+                    "example": "Москва"
+                },
+                "distance_km": {
+                    "type": "number",
+                    "example": 3.25
+                },
+                "expires_at": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-08-15T22:39:54.789179Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 11
+                },
                 "image_url": {
                     "type": "string",
                     "example": "http://example.com/images/car"
@@ -220,14 +2705,47 @@ const docTemplate = `{
                     "type": "boolean",
                     "example": false
                 },
+                "latitude": {
+                    "type": "number",
+                    "x-nullable": true,
+                    "example": 55.7558
+                },
+                "longitude": {
+                    "type": "number",
+                    "x-nullable": true,
+                    "example": 37.6173
+                },
+                "owner_rating": {
+                    "type": "number",
+                    "x-nullable": true,
+                    "example": 4.5
+                },
                 "owner_username": {
                     "type": "string",
                     "example": "CoolUsername"
                 },
+                "previous_price": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 750000
+                },
                 "price": {
                     "type": "integer",
                     "example": 700000
                 },
+                "price_changed_at": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-07-20T10:00:00Z"
+                },
+                "publish_at": {
+                    "type": "string",
+                    "example": "2025-07-20T10:00:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                },
                 "text": {
                     "type": "string",
                     "example": "Продам машину, 120000км пробег"
@@ -238,6 +2756,77 @@ const docTemplate = `{
                 }
             }
         },
+        "announcements.AnnouncementsPatchRequest": {
+            "type": "object",
+            "properties": {
+                "article": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "minLength": 0,
+                    "x-nullable": true,
+                    "example": "Продам старый диван"
+                },
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "x-nullable": true,
+                    "example": {
+                        "condition": "used"
+                    }
+                },
+                "category": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "x-nullable": true,
+                    "example": "furniture"
+                },
+                "city": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "x-nullable": true,
+                    "example": "Москва"
+                },
+                "cost": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 4500
+                },
+                "image_url": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "x-nullable": true,
+                    "example": "http://example.com/images/sofa.jpg"
+                },
+                "latitude": {
+                    "type": "number",
+                    "maximum": 90,
+                    "minimum": -90,
+                    "x-nullable": true,
+                    "example": 55.7558
+                },
+                "longitude": {
+                    "type": "number",
+                    "maximum": 180,
+                    "minimum": -180,
+                    "x-nullable": true,
+                    "example": 37.6173
+                },
+                "publish_at": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-07-20T10:00:00Z"
+                },
+                "text": {
+                    "type": "string",
+                    "maxLength": 2000,
+                    "minLength": 0,
+                    "x-nullable": true,
+                    "example": "Продается диван б/у, в хорошем состоянии, самовывоз."
+                }
+            }
+        },
         "announcements.AnnouncementsPostRequest": {
             "type": "object",
             "required": [
@@ -252,16 +2841,59 @@ const docTemplate = `{
                     "minLength": 5,
                     "example": "Продам старый диван"
                 },
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "x-nullable": true,
+                    "example": {
+                        "assembled": "true",
+                        "condition": "used"
+                    }
+                },
+                "category": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "furniture"
+                },
+                "city": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Москва"
+                },
                 "cost": {
                     "type": "integer",
-                    "minimum": 0,
                     "example": 5000
                 },
+                "draft": {
+                    "type": "boolean",
+                    "example": false
+                },
                 "image_url": {
                     "type": "string",
                     "maxLength": 255,
                     "example": "http://example.com/images/sofa.jpg"
                 },
+                "latitude": {
+                    "type": "number",
+                    "maximum": 90,
+                    "minimum": -90,
+                    "x-nullable": true,
+                    "example": 55.7558
+                },
+                "longitude": {
+                    "type": "number",
+                    "maximum": 180,
+                    "minimum": -180,
+                    "x-nullable": true,
+                    "example": 37.6173
+                },
+                "publish_at": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-07-20T10:00:00Z"
+                },
                 "text": {
                     "type": "string",
                     "maxLength": 2000,
@@ -273,10 +2905,27 @@ const docTemplate = `{
         "announcements.AnnouncementsPostResponse": {
             "type": "object",
             "properties": {
+                "attributes": {
+                    "type": "object",
+                    "x-nullable": true
+                },
+                "category": {
+                    "type": "string",
+                    "example": "cars"
+                },
+                "city": {
+                    "type": "string",
+                    "example": "Москва"
+                },
                 "created_at": {
                     "type": "string",
                     "example": "2025-07-16T22:39:54.789179Z"
                 },
+                "expires_at": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-08-15T22:39:54.789179Z"
+                },
                 "id": {
                     "type": "integer",
                     "example": 11
@@ -285,10 +2934,29 @@ const docTemplate = `{
                     "type": "string",
                     "example": "http://example.com/images/car"
                 },
+                "latitude": {
+                    "type": "number",
+                    "x-nullable": true,
+                    "example": 55.7558
+                },
+                "longitude": {
+                    "type": "number",
+                    "x-nullable": true,
+                    "example": 37.6173
+                },
                 "price": {
                     "type": "integer",
                     "example": 700000
                 },
+                "publish_at": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-07-20T10:00:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                },
                 "text": {
                     "type": "string",
                     "example": "Продается машина, 120000км пробег"
@@ -324,6 +2992,355 @@ const docTemplate = `{
                 }
             }
         },
+        "conversations.ConversationPostResponse": {
+            "type": "object",
+            "properties": {
+                "conversation": {
+                    "$ref": "#/definitions/model.Conversation"
+                },
+                "message": {
+                    "$ref": "#/definitions/model.Message"
+                }
+            }
+        },
+        "conversations.MessagePostRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "text": {
+                    "type": "string",
+                    "maxLength": 2000,
+                    "minLength": 1,
+                    "example": "Здравствуйте! Диван ещё продается?"
+                }
+            }
+        },
+        "conversations.UnreadCountResponse": {
+            "type": "object",
+            "properties": {
+                "unread_count": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "model.AttributeSpec": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "integer",
+                    "example": 2000000
+                },
+                "min": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "name": {
+                    "type": "string",
+                    "example": "mileage"
+                },
+                "required": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "int",
+                        "enum",
+                        "bool",
+                        "range"
+                    ],
+                    "example": "int"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.Category": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AttributeSpec"
+                    },
+                    "x-nullable": true
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Автомобили"
+                },
+                "slug": {
+                    "type": "string",
+                    "example": "cars"
+                }
+            }
+        },
+        "model.Conversation": {
+            "type": "object",
+            "properties": {
+                "announcement_id": {
+                    "type": "integer"
+                },
+                "announcement_title": {
+                    "type": "string"
+                },
+                "buyer_id": {
+                    "type": "integer"
+                },
+                "buyer_username": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_message_at": {
+                    "type": "string"
+                },
+                "seller_id": {
+                    "type": "integer"
+                },
+                "seller_username": {
+                    "type": "string"
+                },
+                "unread_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ListingInbox": {
+            "type": "object",
+            "properties": {
+                "announcement_id": {
+                    "type": "integer"
+                },
+                "announcement_title": {
+                    "type": "string"
+                },
+                "conversations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Conversation"
+                    },
+                    "x-nullable": true
+                },
+                "unread_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Message": {
+            "type": "object",
+            "properties": {
+                "conversation_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "sender_id": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Notification": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "payload": {
+                    "type": "object",
+                    "x-nullable": true
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Offer": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "announcement_id": {
+                    "type": "integer"
+                },
+                "buyer_username": {
+                    "type": "string"
+                },
+                "counter_amount": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Review": {
+            "type": "object",
+            "properties": {
+                "buyer_username": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "rating": {
+                    "type": "integer"
+                },
+                "replied_at": {
+                    "type": "string"
+                },
+                "reply": {
+                    "type": "string"
+                },
+                "seller_username": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UserProfile": {
+            "type": "object",
+            "properties": {
+                "active_announcements": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "avatar_url": {
+                    "type": "string",
+                    "example": "http://example.com/images/avatar.jpg"
+                },
+                "display_name": {
+                    "type": "string",
+                    "example": "Иван"
+                },
+                "rating": {
+                    "type": "number",
+                    "x-nullable": true,
+                    "example": 4.5
+                },
+                "registered_at": {
+                    "type": "string",
+                    "example": "2025-07-16T22:39:54.789179Z"
+                },
+                "reviews_count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "username": {
+                    "type": "string",
+                    "example": "CoolUsername"
+                }
+            }
+        },
+        "offers.OfferPostRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 4500
+                }
+            }
+        },
+        "problem.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "password"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be at least 8 characters long"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "min"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "The request has invalid fields"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/problem.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/problems/validation-error"
+                }
+            }
+        },
         "register.RegisterRequest": {
             "type": "object",
             "required": [
@@ -348,10 +3365,6 @@ const docTemplate = `{
         "register.RegisterResponse": {
             "type": "object",
             "properties": {
-                "password": {
-                    "type": "string",
-                    "example": "CoolPassword"
-                },
                 "user_id": {
                     "type": "integer",
                     "example": 10
@@ -361,6 +3374,75 @@ const docTemplate = `{
                     "example": "CoolUsername"
                 }
             }
+        },
+        "reviews.ReplyPostRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "text": {
+                    "type": "string",
+                    "maxLength": 2000,
+                    "minLength": 1,
+                    "example": "Спасибо за покупку!"
+                }
+            }
+        },
+        "reviews.ReviewPostRequest": {
+            "type": "object",
+            "required": [
+                "rating",
+                "text"
+            ],
+            "properties": {
+                "rating": {
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1,
+                    "example": 5
+                },
+                "text": {
+                    "type": "string",
+                    "maxLength": 2000,
+                    "minLength": 1,
+                    "example": "Диван как в описании, продавец помог с погрузкой."
+                }
+            }
+        },
+        "store.SortOrder": {
+            "type": "string",
+            "enum": [
+                "price_asc",
+                "price_desc",
+                "date_asc",
+                "date_desc",
+                "price_drop",
+                "distance"
+            ],
+            "x-enum-varnames": [
+                "SortPriceAsc",
+                "SortPriceDesc",
+                "SortDateAsc",
+                "SortDateDesc",
+                "SortPriceDrop",
+                "SortDistance"
+            ]
+        },
+        "users.ProfilePatchRequest": {
+            "type": "object",
+            "properties": {
+                "avatar_url": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "http://example.com/images/avatar.jpg"
+                },
+                "display_name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "Иван"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,9 @@
+package docs
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3.1 document of the service written by cmd/openapi
+// from swagger.json.
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -2,228 +2,513 @@ package announcements
 
 import (
 	"encoding/json"
+	"errors"
+	"marketplace-service/internal/categories"
 	"marketplace-service/internal/logger"
 	"marketplace-service/internal/middleware"
 	"marketplace-service/internal/model"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
 	"marketplace-service/internal/store"
 	"marketplace-service/internal/token"
+	"marketplace-service/internal/validation"
 	"net/http"
-	"strconv"
 	"time"
-
-	"github.com/go-playground/validator/v10"
 )
 
-var validate *validator.Validate
+type handler struct {
+	db         store.AnnouncementsStore
+	alerts     store.PriceAlertsStore
+	categories store.CategoriesStore
+	notifier   notifications.Notifier
+	logger     logger.Logger
+	token      *token.Service
+	ttl        time.Duration
+	feedMaxAge time.Duration
+	maxLimit   int
+}
 
-func init() {
-	validate = validator.New()
+type AnnouncementsPostRequest struct {
+	Article    string                     `json:"article" example:"Продам старый диван" validate:"required,min=5,max=200"`
+	Text       string                     `json:"text" example:"Продается диван б/у, в хорошем состоянии, самовывоз. Торг уместен." validate:"required,min=10,max=2000"`
+	ImageURL   string                     `json:"image_url" example:"http://example.com/images/sofa.jpg" validate:"omitempty,safeurl,max=255"`
+	Cost       int32                      `json:"cost" example:"5000" validate:"required,price"`
+	City       string                     `json:"city" example:"Москва" validate:"max=100"`
+	Latitude   *float64                   `json:"latitude" example:"55.7558" validate:"required_with=Longitude,omitempty,min=-90,max=90" extensions:"x-nullable"`
+	Longitude  *float64                   `json:"longitude" example:"37.6173" validate:"required_with=Latitude,omitempty,min=-180,max=180" extensions:"x-nullable"`
+	Category   string                     `json:"category" example:"furniture" validate:"max=64"`
+	Attributes map[string]json.RawMessage `json:"attributes" swaggertype:"object,string" example:"condition:used,assembled:true" extensions:"x-nullable"`
+	Draft      bool                       `json:"draft" example:"false"`
+	PublishAt  *time.Time                 `json:"publish_at" example:"2025-07-20T10:00:00Z" extensions:"x-nullable"`
 }
 
-type handler struct {
-	db     store.AnnouncementsStore
-	logger logger.Logger
-	token  *token.Service
+// AnnouncementsDraftRequest holds the relaxed rules for drafts. The full rules
+// of AnnouncementsPostRequest are checked when the draft is published.
+type AnnouncementsDraftRequest struct {
+	Article    string                     `json:"article" validate:"omitempty,max=200"`
+	Text       string                     `json:"text" validate:"omitempty,max=2000"`
+	ImageURL   string                     `json:"image_url" validate:"omitempty,safeurl,max=255"`
+	Cost       int32                      `json:"cost" validate:"omitempty,price"`
+	City       string                     `json:"city" validate:"max=100"`
+	Latitude   *float64                   `json:"latitude" validate:"required_with=Longitude,omitempty,min=-90,max=90" extensions:"x-nullable"`
+	Longitude  *float64                   `json:"longitude" validate:"required_with=Latitude,omitempty,min=-180,max=180" extensions:"x-nullable"`
+	Category   string                     `json:"category" validate:"max=64"`
+	Attributes map[string]json.RawMessage `json:"attributes" extensions:"x-nullable"`
+	Draft      bool                       `json:"draft"`
+	PublishAt  *time.Time                 `json:"publish_at" extensions:"x-nullable"`
 }
 
-type AnnouncementsPostRequest struct {
-	Article  string `json:"article" example:"Продам старый диван" validate:"required,min=5,max=200"`
-	Text     string `json:"text" example:"Продается диван б/у, в хорошем состоянии, самовывоз. Торг уместен." validate:"required,min=10,max=2000"`
-	ImageURL string `json:"image_url" example:"http://example.com/images/sofa.jpg" validate:"omitempty,url,max=255"`
-	Cost     int32  `json:"cost" example:"5000" validate:"required,min=0"`
+// Validate checks a draft against the relaxed rules and anything else
+// against the full ones.
+func (apr AnnouncementsPostRequest) Validate() error {
+	if apr.Draft {
+		return validation.Struct(AnnouncementsDraftRequest(apr))
+	}
+	return validation.Struct(apr)
 }
 
 type AnnouncementsPostResponse struct {
-	Id            int64     `json:"id" example:"11"`
-	UserId        int64     `json:"user_id" example:"3"`
-	Article       string    `json:"title" example:"Продам машину"`
-	Text          string    `json:"text" example:"Продается машина, 120000км пробег"`
-	CostRubles    int32     `json:"price" example:"700000"`
-	ImageAddress  string    `json:"image_url" example:"http://example.com/images/car"`
-	Date          time.Time `json:"created_at" example:"2025-07-16T22:39:54.789179Z"`
+	Id           int64           `json:"id" example:"11"`
+	UserId       int64           `json:"user_id" example:"3"`
+	Article      string          `json:"title" example:"Продам машину"`
+	Text         string          `json:"text" example:"Продается машина, 120000км пробег"`
+	CostRubles   int32           `json:"price" example:"700000"`
+	ImageAddress string          `json:"image_url" example:"http://example.com/images/car"`
+	City         string          `json:"city" example:"Москва"`
+	Latitude     *float64        `json:"latitude" example:"55.7558" extensions:"x-nullable"`
+	Longitude    *float64        `json:"longitude" example:"37.6173" extensions:"x-nullable"`
+	Category     string          `json:"category" example:"cars"`
+	Attributes   json.RawMessage `json:"attributes" swaggertype:"object" extensions:"x-nullable"`
+	Status       string          `json:"status" example:"active"`
+	ExpiresAt    *time.Time      `json:"expires_at" example:"2025-08-15T22:39:54.789179Z" extensions:"x-nullable"`
+	PublishAt    *time.Time      `json:"publish_at" example:"2025-07-20T10:00:00Z" extensions:"x-nullable"`
+	Date         time.Time       `json:"created_at" example:"2025-07-16T22:39:54.789179Z"`
 }
 
 type AnnouncementsGetResponse struct {
-	OwnerUsername string    `json:"owner_username" example:"CoolUsername"`
-	Article       string    `json:"title" example:"Продам машину"`
-	Text          string    `json:"text" example:"Продам машину, 120000км пробег"`
-	CostRubles    int32     `json:"price" example:"700000"`
-	ImageAddress  string    `json:"image_url" example:"http://example.com/images/car"`
-	IsOwner       *bool     `json:"is_owner,omitempty" example:"false"`
+	Id             int64           `json:"id" example:"11"`
+	OwnerUsername  string          `json:"owner_username" example:"CoolUsername"`
+	OwnerRating    *float64        `json:"owner_rating" example:"4.5" extensions:"x-nullable"`
+	Article        string          `json:"title" example:"Продам машину"`
+	Text           string          `json:"text" example:"Продам машину, 120000км пробег"`
+	CostRubles     int32           `json:"price" example:"700000"`
+	PreviousPrice  *int32          `json:"previous_price" example:"750000" extensions:"x-nullable"`
+	PriceChangedAt *time.Time      `json:"price_changed_at" example:"2025-07-20T10:00:00Z" extensions:"x-nullable"`
+	ImageAddress   string          `json:"image_url" example:"http://example.com/images/car"`
+	City           string          `json:"city" example:"Москва"`
+	Latitude       *float64        `json:"latitude" example:"55.7558" extensions:"x-nullable"`
+	Longitude      *float64        `json:"longitude" example:"37.6173" extensions:"x-nullable"`
+	Category       string          `json:"category" example:"cars"`
+	Attributes     json.RawMessage `json:"attributes" swaggertype:"object" extensions:"x-nullable"`
+	DistanceKm     *float64        `json:"distance_km,omitempty" example:"3.25"`
+	Status         string          `json:"status" example:"active"`
+	ExpiresAt      *time.Time      `json:"expires_at" example:"2025-08-15T22:39:54.789179Z" extensions:"x-nullable"`
+	PublishAt      *time.Time      `json:"publish_at,omitempty" example:"2025-07-20T10:00:00Z"`
+	IsOwner        *bool           `json:"is_owner,omitempty" example:"false"`
 }
 
-func NewHandler(db store.AnnouncementsStore, logger logger.Logger, token *token.Service) *handler {
+// FeedQuery filters and sorts the public list of announcements. The attr.*
+// filters depend on the category and are parsed by the handler.
+type FeedQuery struct {
+	middleware.PageQuery
+	// Sort orders, the first is the most significant. price_drop puts the biggest last price reductions first, distance the nearest to lat and lon, which it requires. Defaults to date_desc
+	SortBy []store.SortOrder `form:"sort_by" defaultItems:"date_desc" enums:"price_asc,price_desc,date_asc,date_desc,price_drop,distance" collectionFormat:"csv" maxItems:"3" uniqueItems:"true"`
+	// Lowest price, inclusive. Must not be greater than max_price
+	MinPrice int `form:"min_price" default:"0" minimum:"0" maximum:"2147483647"`
+	// Highest price, inclusive
+	MaxPrice int `form:"max_price" default:"2147483647" minimum:"0" maximum:"2147483647"`
+	// Show active, reserved or both kinds of announcements
+	Status string `form:"status" default:"active" enums:"active,reserved,all"`
+	// Latitude of the buyer. Together with lon adds distance_km to every announcement
+	Lat *float64 `form:"lat" minimum:"-90" maximum:"90"`
+	// Longitude of the buyer. Set together with lat
+	Lon *float64 `form:"lon" minimum:"-180" maximum:"180"`
+	// Show only announcements within the radius around lat and lon, which it requires
+	RadiusKm *float64 `form:"radius_km" minimum:"0.1" maximum:"20000"`
+	// Show only announcements of the city, case-insensitively. Cannot be combined with radius_km
+	City string `form:"city" maxLength:"100"`
+	// Slug of the category. Required for the attr.* filters
+	Category string `form:"category" maxLength:"64"`
+}
+
+func NewHandler(db store.AnnouncementsStore, alerts store.PriceAlertsStore, categories store.CategoriesStore, notifier notifications.Notifier, logger logger.Logger, token *token.Service, ttl, feedMaxAge time.Duration, maxLimit int) *handler {
 	return &handler{
 		db: db,
+		alerts: alerts,
+		categories: categories,
+		notifier: notifier,
 		logger: logger,
 		token: token,
+		ttl: ttl,
+		feedMaxAge: feedMaxAge,
+		maxLimit: maxLimit,
 	}
 }
 
 func (h *handler) RegisterService(mux *http.ServeMux) {
-	
-	ValidationRules := []middleware.ValidationRule {
-		{
-			ParamName: "page",
-			DefaultValue: "1",
-			Validator: middleware.ValidatePositiveInt,
-			ContextKey: middleware.PageKey,
-		},
-		{
-			ParamName: "limit",
-			DefaultValue: "10",
-			Validator: middleware.ValidatePositiveInt,
-			ContextKey: middleware.LimitKey,
-		},
-		{
-			ParamName: "sort_by",
-			DefaultValue: store.DefaultSorting,
-			Validator: middleware.ValidateByMap(store.ValidSortColumns),
-			ContextKey: middleware.SortKey,
-		},
-		{
-			ParamName: "min_price",
-			DefaultValue: "1",
-			Validator: middleware.ValidatePositiveInt,
-			ContextKey: middleware.MinPrice,
-		},
-		{
-			ParamName: "max_price",
-			DefaultValue: "2147483647",
-			Validator: middleware.ValidatePositiveInt,
-			ContextKey: middleware.MaxPrice,
-		},
-	}
-
 	getAnnouncementsHandler := http.HandlerFunc(h.getAnnouncements)
-	validationMiddleware := middleware.ValidateQueryParams(ValidationRules...)
 	authMiddleware := middleware.OptionalAuthMiddleware(h.token)
 	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
 
 	finalHandler := middleware.Chain(
 		getAnnouncementsHandler,
-		validationMiddleware,
+		middleware.BindQuery[FeedQuery](
+			middleware.AtMost("limit", float64(h.maxLimit)),
+			middleware.Ordered("min_price", "max_price"),
+			middleware.Together("lat", "lon"),
+			middleware.Requires("radius_km", "lat", "lon"),
+			middleware.Exclusive("city", "radius_km"),
+			middleware.RequiresValue("sort_by", string(store.SortDistance), "lat", "lon"),
+		),
+		authMiddleware,
+		loggerMiddleware,
+	)
+
+	ownerAnnouncementsHandler := middleware.Chain(
+		http.HandlerFunc(h.getOwnerAnnouncements),
+		middleware.BindQuery[middleware.PageQuery](middleware.AtMost("limit", float64(h.maxLimit))),
 		authMiddleware,
 		loggerMiddleware,
 	)
 
-	mux.HandleFunc("POST /api/v1/announcements", middleware.AuthMiddleware(h.token, h.createAnnouncement))
+	mux.HandleFunc("POST /api/v1/announcements", middleware.AuthMiddleware(h.token, middleware.BindBody[AnnouncementsPostRequest](h.createAnnouncement)))
 	mux.Handle("GET /api/v1/announcements", finalHandler)
+	mux.Handle("GET /api/v1/users/{username}/announcements", ownerAnnouncementsHandler)
+	mux.Handle("GET /api/v1/announcements/drafts", middleware.Chain(
+		middleware.AuthMiddleware(h.token, h.getDrafts),
+		middleware.BindQuery[middleware.PageQuery](middleware.AtMost("limit", float64(h.maxLimit))),
+		loggerMiddleware,
+	))
+
+	mux.Handle("GET /api/v1/announcements/{id}", middleware.Chain(http.HandlerFunc(h.getAnnouncement), authMiddleware, loggerMiddleware))
+	mux.Handle("PATCH /api/v1/announcements/{id}", loggerMiddleware(middleware.AuthMiddleware(h.token, middleware.BindBody[AnnouncementsPatchRequest](h.updateAnnouncement))))
+	mux.Handle("DELETE /api/v1/announcements/{id}", loggerMiddleware(middleware.AuthMiddleware(h.token, h.deleteAnnouncement)))
+	mux.Handle("POST /api/v1/announcements/{id}/publish", loggerMiddleware(middleware.AuthMiddleware(h.token, h.publishAnnouncement)))
+	mux.Handle("POST /api/v1/announcements/{id}/renew", loggerMiddleware(middleware.AuthMiddleware(h.token, h.renewAnnouncement)))
+	mux.Handle("POST /api/v1/announcements/{id}/price-alerts", loggerMiddleware(middleware.AuthMiddleware(h.token, h.subscribeToPriceDrops)))
+	mux.Handle("DELETE /api/v1/announcements/{id}/price-alerts", loggerMiddleware(middleware.AuthMiddleware(h.token, h.unsubscribeFromPriceDrops)))
+}
+
+func toGetResponse(announcements []model.Announcement) []AnnouncementsGetResponse {
+	response := make([]AnnouncementsGetResponse, len(announcements))
+
+	for i, an := range announcements {
+		response[i] = toSingleGetResponse(an)
+	}
+
+	return response
+}
+
+func toSingleGetResponse(an model.Announcement) AnnouncementsGetResponse {
+	var response AnnouncementsGetResponse
+
+	response.Id = an.Id
+	response.Article = an.Article
+	response.IsOwner = an.IsOwner
+	response.ImageAddress = an.ImageAddress
+	response.City = an.City
+	response.Latitude = an.Latitude
+	response.Longitude = an.Longitude
+	response.DistanceKm = an.DistanceKm
+	response.Category = an.Category
+	response.Attributes = an.Attributes
+	response.CostRubles = an.CostRubles
+	response.PreviousPrice = an.PreviousPrice
+	response.PriceChangedAt = an.PriceChangedAt
+	response.Text = an.Text
+	response.OwnerUsername = an.OwnerUsername
+	response.OwnerRating = an.OwnerRating
+	response.Status = an.Status
+	response.ExpiresAt = an.ExpiresAt
+	response.PublishAt = an.PublishAt
+
+	return response
+}
+
+// loadCategory fetches the category of the announcement, nil if it has none.
+func loadCategory(db store.CategoriesStore, an model.Announcement) (*model.Category, error) {
+	if an.CategoryId == nil {
+		return nil, nil
+	}
+	return db.GetCategoryById(*an.CategoryId)
+}
+
+// publishable checks the announcement against the rules of a published one.
+func publishable(an model.Announcement, category *model.Category) error {
+	err := validation.Struct(AnnouncementsPostRequest{
+		Article:  an.Article,
+		Text:     an.Text,
+		ImageURL: an.ImageAddress,
+		Cost:     an.CostRubles,
+	})
+	if err != nil || category == nil {
+		return err
+	}
+
+	return categories.CheckAttributes(category, an.Attributes, false)
+}
+
+// applyCategory sets the category and the attributes of the announcement after
+// checking them against the schema of the category. Drafts may miss required
+// attributes. It writes the error response itself.
+func (h *handler) applyCategory(w http.ResponseWriter, r *http.Request, an *model.Announcement, slug string, raw map[string]json.RawMessage) bool {
+	if slug == "" {
+		if len(raw) > 0 {
+			problem.Error(w, "Attributes require a category", http.StatusBadRequest)
+			return false
+		}
+		an.CategoryId = nil
+		an.Category = ""
+		an.Attributes = nil
+		return true
+	}
+
+	category, err := h.categories.GetCategoryBySlug(slug)
+	if err != nil {
+		if errors.Is(err, store.ErrCategoryNotFound) {
+			problem.Error(w, "Unknown category", http.StatusBadRequest)
+			return false
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+
+	attributes, err := categories.ValidateAttributes(category, raw, an.Status == model.AnnouncementDraft)
+	if err != nil {
+		validation.WriteError(w, r, err)
+		return false
+	}
+
+	an.CategoryId = &category.Id
+	an.Category = category.Slug
+	an.Attributes = attributes
+	return true
 }
 
 // Announcement creation
 // @Summary      Create an announcement
-// @Description  Create an announcement for authorized users.
+// @Description  Create an announcement for authorized users. The announcement is archived when its time to live is over unless the owner renews it.
+// @Description  With draft set the announcement is saved as a draft: the fields are checked only for their length and the full rules apply when it is published. A draft with publish_at is published automatically at that time.
 // @Tags         Announcements 
 // @Accept       json
 // @Produce      json
 // @Param        request body AnnouncementsPostRequest true "Announcement details"
+// @Param        Idempotency-Key  header  string  false  "Repeating the request with the same key returns the first response"
 // @Success      201 {object} AnnouncementsPostResponse "Announcement successfully created"
-// @Failure      400 "Invalid request payload"
-// @Failure      500 "Internal server error"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
 // @Router       /api/v1/announcements [post]
 // @Security     Bearer
 func (h *handler) createAnnouncement (w http.ResponseWriter, r *http.Request) {
-	var apr AnnouncementsPostRequest
+	apr := middleware.Body[AnnouncementsPostRequest](r)
 
-	err := json.NewDecoder(r.Body).Decode(&apr)
-
-	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
+	if apr.PublishAt != nil {
+		if !apr.Draft {
+			problem.Error(w, "publish_at can be set only for drafts", http.StatusBadRequest)
+			return
+		}
+		if !apr.PublishAt.After(time.Now()) {
+			problem.Error(w, "publish_at must be in the future", http.StatusBadRequest)
+			return
+		}
 	}
 
-	if err := validate.Struct(apr); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	userId, _ := middleware.GetUserId(r)
 
-	token := token.ExtractToken(r)
-	userId, _ := h.token.ValidateToken(token)
-	
 	var an model.Announcement
 	
 	an.Article = apr.Article
 	an.Text = apr.Text
+	an.ImageAddress = apr.ImageURL
 	an.CostRubles = apr.Cost
+	an.City = apr.City
+	an.Latitude = apr.Latitude
+	an.Longitude = apr.Longitude
 
-	id, err := strconv.Atoi(userId)
-	an.UserId = int64(id)
+	if apr.Draft {
+		an.Status = model.AnnouncementDraft
+		an.PublishAt = apr.PublishAt
+	} else {
+		an.Status = model.AnnouncementActive
+		expiresAt := time.Now().Add(h.ttl)
+		an.ExpiresAt = &expiresAt
+	}
 
-	err = h.db.CreateAnnouncement(&an)
+	if !h.applyCategory(w, r, &an, apr.Category, apr.Attributes) {
+		return
+	}
+
+	an.UserId = int64(userId)
+
+	err := h.db.CreateAnnouncement(&an)
 	if err != nil {
 		h.logger.Info(err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var response AnnouncementsPostResponse
+	var resp AnnouncementsPostResponse
 
-	response.Article = an.Article
-	response.Text = an.Text
-	response.CostRubles = an.CostRubles
-	response.ImageAddress = an.ImageAddress
-	response.Date = an.Date
-	response.Id = an.Id
-	response.UserId = an.UserId
-	
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	resp.Article = an.Article
+	resp.Text = an.Text
+	resp.CostRubles = an.CostRubles
+	resp.ImageAddress = an.ImageAddress
+	resp.City = an.City
+	resp.Latitude = an.Latitude
+	resp.Longitude = an.Longitude
+	resp.Category = an.Category
+	resp.Attributes = an.Attributes
+	resp.Status = an.Status
+	resp.ExpiresAt = an.ExpiresAt
+	resp.PublishAt = an.PublishAt
+	resp.Date = an.Date
+	resp.Id = an.Id
+	resp.UserId = an.UserId
+
+	response.JSON(w, h.logger, http.StatusCreated, resp)
 }
 
 
 // GetAnnouncements gets a paginated list of announcements
 // @Summary      Get announcements list
-// @Description  Get a paginated list of announcements. This endpoint is public.
+// @Description  Get a paginated list of announcements. Expired announcements are not shown. This endpoint is public.
+// @Description  Responses carry a weak ETag and Last-Modified of the page; a request with a matching If-None-Match gets 304. Anonymous pages may be cached publicly for a short time, pages of authenticated users are private.
 // @Tags         Announcements
 // @Produce      json
-// @Param        page     query      int    false  "Page number for pagination (starts from 1). Defaults to 1."
-// @Param        limit    query      int    false  "Number of items per page. Defaults to 10."
-// @Param        sort_by  query      string false "Sort order for announcements." Enums(price_asc, price_desc, date_asc, date_desc)
-// @Param        min_price query     int false "All announcements with price more than min_price. Default is 0"
-// @Param        max_price query     int false "All announcements with price less than max_price. Default is (1 << 31) - 1"
+// @Param        query     query     FeedQuery false "Filters, sorting and page"
+// @Param        attr.name query     string false "Filter by an attribute of the category: attr.X for any attribute, attr.X_min and attr.X_max for int attributes"
+// @Param        If-None-Match header string false "ETag of a cached page"
 // @Success      200      {array}    AnnouncementsGetResponse
-// @Failure      400      "Invalid page or limit parameter"
-// @Failure      500      "Internal server error"
+// @Header       200      {string}   ETag           "Weak entity tag of the page"
+// @Header       200      {string}   Last-Modified  "Time of the latest change among the announcements of the page"
+// @Header       200      {string}   Cache-Control  "public with max-age for anonymous users, private and no-cache otherwise"
+// @Success      304      "The cached page is still valid"
+// @Failure      400      {object}  problem.Problem  "Invalid query parameter"
+// @Failure      500      {object}  problem.Problem  "Internal server error"
 // @Router       /api/v1/announcements [get]
 // @Security     Bearer
 func (h *handler) getAnnouncements(w http.ResponseWriter, r *http.Request) {
+	query := middleware.Query[FeedQuery](r)
 
-	page := r.Context().Value(middleware.PageKey).(int)
-	limit := r.Context().Value(middleware.LimitKey).(int)
-	sortBy := r.Context().Value(middleware.SortKey).(string)
+	var near *store.Nearby
+	if query.Lat != nil {
+		near = &store.Nearby{Lat: *query.Lat, Lon: *query.Lon}
+		if query.RadiusKm != nil {
+			near.RadiusKm = *query.RadiusKm
+		}
+	}
+
+	var category *model.Category
+	if slug := query.Category; slug != "" {
+		var err error
+		category, err = h.categories.GetCategoryBySlug(slug)
+		if err != nil {
+			if errors.Is(err, store.ErrCategoryNotFound) {
+				problem.Error(w, "Unknown category", http.StatusBadRequest)
+				return
+			}
+			h.logger.Info(err)
+			problem.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	attributes, err := categories.ParseFilters(category, r.URL.Query())
+	if err != nil {
+		validation.WriteError(w, r, err)
+		return
+	}
 
-	minPrice := r.Context().Value(middleware.MinPrice).(int)
-	maxPrice := r.Context().Value(middleware.MaxPrice).(int)
+	var filter *store.CategoryFilter
+	if category != nil {
+		filter = &store.CategoryFilter{Id: category.Id, Attributes: attributes}
+	}
 
-	currentUserIdString, _ := h.token.ValidateToken(token.ExtractToken(r))
-	currentUserId, err := strconv.Atoi(currentUserIdString)
-	h.logger.Debug(currentUserId, err)
+	currentUserId, _ := middleware.GetUserId(r)
 
-	announcements, err := h.db.GetAnnouncementsByPage(page, limit, currentUserId, sortBy, minPrice, maxPrice)
+	announcements, err := h.db.GetAnnouncementsByPage(store.AnnouncementQuery{
+		Filter: store.AnnouncementFilter{
+			MinPrice: query.MinPrice,
+			MaxPrice: query.MaxPrice,
+			Status:   query.Status,
+			City:     query.City,
+			Near:     near,
+			Category: filter,
+		},
+		Sort:          query.SortBy,
+		Page:          query.Page,
+		Limit:         query.Limit,
+		CurrentUserId: currentUserId,
+	})
 	h.logger.Debug(announcements)
 
 	if err != nil {
 		h.logger.Info(err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		problem.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
-	
-	response := make([]AnnouncementsGetResponse, len(announcements))
 
-	for i, an := range announcements {
-		response[i].Article       = an.Article
-		response[i].IsOwner       = an.IsOwner
-		response[i].ImageAddress  = an.ImageAddress
-		response[i].CostRubles    = an.CostRubles
-		response[i].Text          = an.Text
-		response[i].OwnerUsername = an.OwnerUsername
+	h.writeFeed(w, r, announcements, currentUserId)
+}
+
+// GetOwnerAnnouncements gets announcements of a user
+// @Summary      Get announcements of a user
+// @Description  Get a paginated list of announcements published by the user, newest first. This endpoint is public.
+// @Tags         Announcements
+// @Produce      json
+// @Param        username path       string true   "Username of the owner"
+// @Param        query    query      middleware.PageQuery  false  "Page"
+// @Success      200      {array}    AnnouncementsGetResponse
+// @Failure      400      {object}  problem.Problem  "Invalid page or limit parameter"
+// @Failure      404      {object}  problem.Problem  "User not found"
+// @Failure      500      {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/users/{username}/announcements [get]
+// @Security     Bearer
+func (h *handler) getOwnerAnnouncements(w http.ResponseWriter, r *http.Request) {
+	query := middleware.Query[middleware.PageQuery](r)
+
+	currentUserId, _ := middleware.GetUserId(r)
+
+	announcements, err := h.db.GetAnnouncementsByOwner(r.PathValue("username"), query.Page, query.Limit, currentUserId)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			problem.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal error", http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err = json.NewEncoder(w).Encode(response); err != nil {
+	response.JSON(w, h.logger, http.StatusOK, toGetResponse(announcements))
+}
+
+// GetDrafts gets drafts of the current user
+// @Summary      Get own drafts
+// @Description  Get a paginated list of the drafts of the authorized user, recently changed first.
+// @Tags         Announcements
+// @Produce      json
+// @Param        query    query      middleware.PageQuery  false  "Page"
+// @Success      200      {array}    AnnouncementsGetResponse
+// @Failure      400      {object}  problem.Problem  "Invalid page or limit parameter"
+// @Failure      401      {object}  problem.Problem  "Unauthorized"
+// @Failure      500      {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/drafts [get]
+// @Security     Bearer
+func (h *handler) getDrafts(w http.ResponseWriter, r *http.Request) {
+	query := middleware.Query[middleware.PageQuery](r)
+
+	userId, _ := middleware.GetUserId(r)
+
+	drafts, err := h.db.GetDraftsByOwner(int64(userId), query.Page, query.Limit)
+	if err != nil {
 		h.logger.Info(err)
+		problem.Error(w, "Internal error", http.StatusInternalServerError)
+		return
 	}
+
+	response.JSON(w, h.logger, http.StatusOK, toGetResponse(drafts))
 }
@@ -0,0 +1,65 @@
+package announcements
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/problem"
+	"net/http"
+	"time"
+)
+
+// writeFeed writes a page of the feed with validators derived from the page
+// itself: a weak ETag hashed from the encoded body and Last-Modified of the
+// latest changed announcement. A matching If-None-Match is answered with 304.
+//
+// Last-Modified is informational only and If-Modified-Since is not honored:
+// an announcement leaving the page does not move the time forward.
+//
+// Anonymous pages are the same for everyone and may be kept by shared caches
+// for feedMaxAge. Pages of authenticated users carry is_owner, so they are
+// private and revalidated on every use.
+func (h *handler) writeFeed(w http.ResponseWriter, r *http.Request, announcements []model.Announcement, currentUserId int) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(toGetResponse(announcements)); err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	tag := fmt.Sprintf(`W/"%s"`, hex.EncodeToString(sum[:16]))
+
+	header := w.Header()
+	header.Set("ETag", tag)
+	header.Add("Vary", "Authorization")
+	if currentUserId > 0 {
+		header.Set("Cache-Control", "private, no-cache")
+	} else {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.feedMaxAge.Seconds())))
+	}
+
+	var lastModified time.Time
+	for _, an := range announcements {
+		if an.UpdatedAt.After(lastModified) {
+			lastModified = an.UpdatedAt
+		}
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if matchWeak(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		h.logger.Info(err)
+	}
+}
@@ -0,0 +1,517 @@
+package announcements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/validation"
+	"net/http"
+	"time"
+)
+
+// AnnouncementsPatchRequest changes only the fields that are present. The
+// minimal lengths apply to published announcements only and are left out of
+// the documented schema, which drafts are checked against too.
+type AnnouncementsPatchRequest struct {
+	Article    *string                    `json:"article" example:"Продам старый диван" validate:"omitempty,min=5,max=200" minLength:"0" extensions:"x-nullable"`
+	Text       *string                    `json:"text" example:"Продается диван б/у, в хорошем состоянии, самовывоз." validate:"omitempty,min=10,max=2000" minLength:"0" extensions:"x-nullable"`
+	ImageURL   *string                    `json:"image_url" example:"http://example.com/images/sofa.jpg" validate:"omitempty,max=255,eq=|safeurl" extensions:"x-nullable"`
+	Cost       *int32                     `json:"cost" example:"4500" validate:"omitempty,price" extensions:"x-nullable"`
+	City       *string                    `json:"city" example:"Москва" validate:"omitempty,max=100" extensions:"x-nullable"`
+	Latitude   *float64                   `json:"latitude" example:"55.7558" validate:"required_with=Longitude,omitempty,min=-90,max=90" extensions:"x-nullable"`
+	Longitude  *float64                   `json:"longitude" example:"37.6173" validate:"required_with=Latitude,omitempty,min=-180,max=180" extensions:"x-nullable"`
+	Category   *string                    `json:"category" example:"furniture" validate:"omitempty,max=64" extensions:"x-nullable"`
+	Attributes map[string]json.RawMessage `json:"attributes" swaggertype:"object,string" example:"condition:used" extensions:"x-nullable"`
+	PublishAt  *time.Time                 `json:"publish_at" example:"2025-07-20T10:00:00Z" extensions:"x-nullable"`
+}
+
+// AnnouncementsDraftPatchRequest holds the relaxed rules for the changes of
+// drafts, like AnnouncementsDraftRequest does for new ones.
+type AnnouncementsDraftPatchRequest struct {
+	Article    *string                    `json:"article" validate:"omitempty,max=200"`
+	Text       *string                    `json:"text" validate:"omitempty,max=2000"`
+	ImageURL   *string                    `json:"image_url" validate:"omitempty,max=255,eq=|safeurl"`
+	Cost       *int32                     `json:"cost" validate:"omitempty,price"`
+	City       *string                    `json:"city" validate:"omitempty,max=100"`
+	Latitude   *float64                   `json:"latitude" validate:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude  *float64                   `json:"longitude" validate:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Category   *string                    `json:"category" validate:"omitempty,max=64"`
+	Attributes map[string]json.RawMessage `json:"attributes"`
+	PublishAt  *time.Time                 `json:"publish_at"`
+}
+
+// Validate checks the relaxed rules every change has to pass. The full rules
+// are checked by updateAnnouncement once it knows the announcement is not a
+// draft.
+func (apr AnnouncementsPatchRequest) Validate() error {
+	return validation.Struct(AnnouncementsDraftPatchRequest(apr))
+}
+
+// loadOwnAnnouncement fetches the announcement from the path and makes sure it
+// belongs to the current user. It writes the error response itself.
+func (h *handler) loadOwnAnnouncement(w http.ResponseWriter, r *http.Request) (*model.Announcement, bool) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid announcement id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userId, _ := middleware.GetUserId(r)
+
+	an, err := h.db.GetAnnouncementById(id, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotFound) {
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+			return nil, false
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if an.UserId != int64(userId) {
+		problem.Error(w, "Only the owner can change the announcement", http.StatusForbidden)
+		return nil, false
+	}
+
+	return an, true
+}
+
+// etag is the entity tag of the announcement version.
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// checkIfMatch makes sure the request is made against the current version of
+// the announcement. It answers 428 if the If-Match header is missing and 412
+// if none of its tags match. The tags are compared strongly (RFC 9110,
+// section 13.1.1), so a weak tag never matches.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, an *model.Announcement) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		problem.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return false
+	}
+
+	current := etag(an.Version)
+	if matchStrong(header, current) {
+		return true
+	}
+
+	w.Header().Set("ETag", current)
+	problem.Error(w, "The announcement has been changed", http.StatusPreconditionFailed)
+	return false
+}
+
+// writeAnnouncement writes the announcement with its version as the ETag.
+func (h *handler) writeAnnouncement(w http.ResponseWriter, an *model.Announcement) {
+	w.Header().Set("ETag", etag(an.Version))
+	response.JSON(w, h.logger, http.StatusOK, toSingleGetResponse(*an))
+}
+
+// visible reports whether the user may see the announcement. Drafts are
+// visible only to their owners.
+func visible(an *model.Announcement, userId int) bool {
+	return an.Status != model.AnnouncementDraft || an.UserId == int64(userId)
+}
+
+// GetAnnouncement gets a single announcement
+// @Summary      Get an announcement
+// @Description  Get a single announcement by id. This endpoint is public. The ETag header holds the version of the announcement to send in If-Match when changing it.
+// @Tags         Announcements
+// @Produce      json
+// @Param        id   path      int  true  "Announcement id"
+// @Success      200  {object}  AnnouncementsGetResponse
+// @Header       200  {string}  ETag  "Version of the announcement"
+// @Failure      400  {object}  problem.Problem  "Invalid announcement id"
+// @Failure      404  {object}  problem.Problem  "Announcement not found"
+// @Failure      500  {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id} [get]
+// @Security     Bearer
+func (h *handler) getAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	currentUserId, _ := middleware.GetUserId(r)
+
+	an, err := h.db.GetAnnouncementById(id, currentUserId)
+	if err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotFound) {
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !visible(an, currentUserId) {
+		problem.Error(w, "Announcement not found", http.StatusNotFound)
+		return
+	}
+
+	h.writeAnnouncement(w, an)
+}
+
+// Announcement editing
+// @Summary      Edit an announcement
+// @Description  Change the fields of an own announcement. The If-Match header must hold the current ETag of the announcement. Omitted fields are left as they are. Price changes are kept in the price history and reported to the users watching the price. publish_at can be changed only for drafts. Changes of drafts are checked only for the length of the fields, like new drafts.
+// @Description  Attributes replace the previous ones and are checked against the category; when only the category changes the previous attributes are checked against the new one.
+// @Tags         Announcements
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                        true  "Announcement id"
+// @Param        If-Match header string                     true  "ETag of the announcement version being changed"
+// @Param        request  body  AnnouncementsPatchRequest  true  "Fields to change"
+// @Success      200 {object} AnnouncementsGetResponse "Announcement successfully updated"
+// @Header       200 {string} ETag "New version of the announcement"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "Not the owner of the announcement"
+// @Failure      404 {object}  problem.Problem  "Announcement not found"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      412 {object}  problem.Problem  "The announcement has been changed since it was read"
+// @Failure      428 {object}  problem.Problem  "If-Match header is required"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id} [patch]
+// @Security     Bearer
+func (h *handler) updateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	an, ok := h.loadOwnAnnouncement(w, r)
+	if !ok || !checkIfMatch(w, r, an) {
+		return
+	}
+
+	apr := middleware.Body[AnnouncementsPatchRequest](r)
+	if an.Status != model.AnnouncementDraft {
+		if err := validation.Struct(apr); err != nil {
+			validation.WriteError(w, r, err)
+			return
+		}
+	}
+
+	if apr.Article != nil {
+		an.Article = *apr.Article
+	}
+	if apr.Text != nil {
+		an.Text = *apr.Text
+	}
+	if apr.ImageURL != nil {
+		an.ImageAddress = *apr.ImageURL
+	}
+	if apr.Cost != nil {
+		an.CostRubles = *apr.Cost
+	}
+	if apr.City != nil {
+		an.City = *apr.City
+	}
+	if apr.Latitude != nil {
+		an.Latitude = apr.Latitude
+		an.Longitude = apr.Longitude
+	}
+	if apr.PublishAt != nil {
+		if an.Status != model.AnnouncementDraft {
+			problem.Error(w, "publish_at can be set only for drafts", http.StatusBadRequest)
+			return
+		}
+		if !apr.PublishAt.After(time.Now()) {
+			problem.Error(w, "publish_at must be in the future", http.StatusBadRequest)
+			return
+		}
+		an.PublishAt = apr.PublishAt
+	}
+	if apr.Category != nil || apr.Attributes != nil {
+		slug := an.Category
+		if apr.Category != nil {
+			slug = *apr.Category
+		}
+
+		raw := apr.Attributes
+		if raw == nil && len(an.Attributes) > 0 {
+			if err := json.Unmarshal(an.Attributes, &raw); err != nil {
+				h.logger.Info(err)
+				problem.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !h.applyCategory(w, r, an, slug, raw) {
+			return
+		}
+	}
+
+	oldPrice, err := h.db.UpdateAnnouncement(an)
+	if err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotFound) {
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, store.ErrVersionMismatch) {
+			problem.Error(w, "The announcement has been changed", http.StatusPreconditionFailed)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if an.CostRubles < oldPrice {
+		h.notifyPriceDrop(an, oldPrice)
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	an, err = h.db.GetAnnouncementById(an.Id, userId)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeAnnouncement(w, an)
+}
+
+func (h *handler) notifyPriceDrop(an *model.Announcement, oldPrice int32) {
+	subscribers, err := h.alerts.GetPriceDropSubscribers(an.Id)
+	if err != nil {
+		h.logger.Info(err)
+		return
+	}
+
+	for _, userId := range subscribers {
+		err := h.notifier.Notify(userId, "announcement.price_drop", map[string]any{
+			"announcement_id": an.Id,
+			"old_price":       oldPrice,
+			"new_price":       an.CostRubles,
+		})
+		if err != nil {
+			h.logger.Info(err)
+		}
+	}
+}
+
+// Announcement deletion
+// @Summary      Delete an announcement
+// @Description  Delete an own announcement. The If-Match header must hold the current ETag of the announcement.
+// @Tags         Announcements
+// @Param        id        path    int     true  "Announcement id"
+// @Param        If-Match  header  string  true  "ETag of the announcement version being deleted"
+// @Success      204  "Announcement successfully deleted"
+// @Failure      400  {object}  problem.Problem  "Invalid announcement id"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Not the owner of the announcement"
+// @Failure      404  {object}  problem.Problem  "Announcement not found"
+// @Failure      412  {object}  problem.Problem  "The announcement has been changed since it was read"
+// @Failure      428  {object}  problem.Problem  "If-Match header is required"
+// @Failure      500  {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id} [delete]
+// @Security     Bearer
+func (h *handler) deleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	an, ok := h.loadOwnAnnouncement(w, r)
+	if !ok || !checkIfMatch(w, r, an) {
+		return
+	}
+
+	if err := h.db.DeleteAnnouncement(an.Id, an.Version); err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotFound) {
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, store.ErrVersionMismatch) {
+			problem.Error(w, "The announcement has been changed", http.StatusPreconditionFailed)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Draft publishing
+// @Summary      Publish a draft
+// @Description  Publish an own draft right away. The draft must pass the same validation as a new announcement.
+// @Tags         Announcements
+// @Produce      json
+// @Param        id   path      int  true  "Announcement id"
+// @Success      200  {object}  AnnouncementsGetResponse "Draft successfully published"
+// @Failure      400  {object}  problem.Problem  "The draft does not pass the validation"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Not the owner of the announcement"
+// @Failure      404  {object}  problem.Problem  "Announcement not found"
+// @Failure      409  {object}  problem.Problem  "The announcement is not a draft"
+// @Failure      500  {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id}/publish [post]
+// @Security     Bearer
+func (h *handler) publishAnnouncement(w http.ResponseWriter, r *http.Request) {
+	an, ok := h.loadOwnAnnouncement(w, r)
+	if !ok {
+		return
+	}
+
+	if an.Status != model.AnnouncementDraft {
+		problem.Error(w, "The announcement is not a draft", http.StatusConflict)
+		return
+	}
+
+	category, err := loadCategory(h.categories, *an)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := publishable(*an, category); err != nil {
+		validation.WriteError(w, r, err)
+		return
+	}
+
+	if err := h.db.PublishAnnouncement(an.Id, time.Now().Add(h.ttl)); err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotDraft) {
+			problem.Error(w, "The announcement is not a draft", http.StatusConflict)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	an, err = h.db.GetAnnouncementById(an.Id, userId)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeAnnouncement(w, an)
+}
+
+// Announcement renewal
+// @Summary      Renew an announcement
+// @Description  Extend an own announcement for one more time to live starting from now. Archived announcements come back to the feed.
+// @Tags         Announcements
+// @Produce      json
+// @Param        id   path      int  true  "Announcement id"
+// @Success      200  {object}  AnnouncementsGetResponse "Announcement successfully renewed"
+// @Failure      400  {object}  problem.Problem  "Invalid announcement id"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Not the owner of the announcement"
+// @Failure      404  {object}  problem.Problem  "Announcement not found"
+// @Failure      409  {object}  problem.Problem  "Drafts are published, not renewed"
+// @Failure      500  {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id}/renew [post]
+// @Security     Bearer
+func (h *handler) renewAnnouncement(w http.ResponseWriter, r *http.Request) {
+	an, ok := h.loadOwnAnnouncement(w, r)
+	if !ok {
+		return
+	}
+
+	if an.Status == model.AnnouncementDraft {
+		problem.Error(w, "Drafts are published, not renewed", http.StatusConflict)
+		return
+	}
+
+	if err := h.db.RenewAnnouncement(an.Id, time.Now().Add(h.ttl)); err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotFound) {
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	an, err := h.db.GetAnnouncementById(an.Id, userId)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeAnnouncement(w, an)
+}
+
+// Price drop subscription
+// @Summary      Watch the price
+// @Description  Get a notification every time the price of the announcement goes down.
+// @Tags         Announcements
+// @Param        id   path      int  true  "Announcement id"
+// @Success      204  "Subscribed"
+// @Failure      400  {object}  problem.Problem  "Invalid announcement id"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      404  {object}  problem.Problem  "Announcement not found"
+// @Failure      500  {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id}/price-alerts [post]
+// @Security     Bearer
+func (h *handler) subscribeToPriceDrops(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+
+	an, err := h.db.GetAnnouncementById(id, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotFound) {
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !visible(an, userId) {
+		problem.Error(w, "Announcement not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.alerts.SubscribeToPriceDrops(int64(userId), id); err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Price drop unsubscription
+// @Summary      Stop watching the price
+// @Description  Stop getting notifications about price drops of the announcement.
+// @Tags         Announcements
+// @Param        id   path      int  true  "Announcement id"
+// @Success      204  "Unsubscribed"
+// @Failure      400  {object}  problem.Problem  "Invalid announcement id"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      500  {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id}/price-alerts [delete]
+// @Security     Bearer
+func (h *handler) unsubscribeFromPriceDrops(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+
+	if err := h.alerts.UnsubscribeFromPriceDrops(int64(userId), id); err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
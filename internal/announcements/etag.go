@@ -0,0 +1,84 @@
+package announcements
+
+import "strings"
+
+// entityTag is an entity tag of RFC 9110, section 8.8.3.
+type entityTag struct {
+	weak   bool
+	opaque string
+}
+
+// parseETags parses a single tag of the ETag header or the list of
+// If-Match and If-None-Match. any reports "*". Malformed tags are skipped.
+func parseETags(header string) (tags []entityTag, any bool) {
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			return tags, any
+		}
+
+		if rest[0] == '*' {
+			any = true
+			rest = rest[1:]
+			continue
+		}
+
+		var tag entityTag
+		if strings.HasPrefix(rest, "W/") {
+			tag.weak = true
+			rest = rest[2:]
+		}
+
+		// The opaque part may hold commas, so the list is split by the quotes.
+		end := -1
+		if strings.HasPrefix(rest, `"`) {
+			end = strings.IndexByte(rest[1:], '"')
+		}
+		if end < 0 {
+			if comma := strings.IndexByte(rest, ','); comma >= 0 {
+				rest = rest[comma:]
+				continue
+			}
+			return tags, any
+		}
+
+		tag.opaque = rest[1 : end+1]
+		tags = append(tags, tag)
+		rest = rest[end+2:]
+	}
+}
+
+// matchStrong reports whether a tag of the header is the current one by the
+// strong comparison used for If-Match: weak tags never match.
+func matchStrong(header, current string) bool {
+	return match(header, current, func(a, b entityTag) bool {
+		return !a.weak && !b.weak && a.opaque == b.opaque
+	})
+}
+
+// matchWeak reports whether a tag of the header is the current one by the
+// weak comparison used for If-None-Match: only the opaque parts are compared.
+func matchWeak(header, current string) bool {
+	return match(header, current, func(a, b entityTag) bool {
+		return a.opaque == b.opaque
+	})
+}
+
+func match(header, current string, equal func(a, b entityTag) bool) bool {
+	tags, any := parseETags(header)
+	if any {
+		return true
+	}
+
+	currentTags, _ := parseETags(current)
+	if len(currentTags) != 1 {
+		return false
+	}
+	for _, tag := range tags {
+		if equal(tag, currentTags[0]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package announcements
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"marketplace-service/internal/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseETags(t *testing.T) {
+	tests := []struct {
+		header string
+		tags   []entityTag
+		any    bool
+	}{
+		{``, nil, false},
+		{`"3"`, []entityTag{{opaque: "3"}}, false},
+		{`W/"3"`, []entityTag{{weak: true, opaque: "3"}}, false},
+		{` "1", W/"2" ,"3"`, []entityTag{{opaque: "1"}, {weak: true, opaque: "2"}, {opaque: "3"}}, false},
+		{`"a,b", "c"`, []entityTag{{opaque: "a,b"}, {opaque: "c"}}, false},
+		{`""`, []entityTag{{opaque: ""}}, false},
+		{`*`, nil, true},
+		{`3, "4"`, []entityTag{{opaque: "4"}}, false},
+		{`"unterminated`, nil, false},
+	}
+
+	for _, tt := range tests {
+		tags, any := parseETags(tt.header)
+		if !slices.Equal(tags, tt.tags) || any != tt.any {
+			t.Errorf("parseETags(%q) = %v, %v, want %v, %v", tt.header, tags, any, tt.tags, tt.any)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header, current string
+		strong, weak    bool
+	}{
+		{`"3"`, `"3"`, true, true},
+		{`"2", "3"`, `"3"`, true, true},
+		{`W/"3"`, `"3"`, false, true},
+		{`"3"`, `W/"3"`, false, true},
+		{`W/"3"`, `W/"3"`, false, true},
+		{`"4"`, `"3"`, false, false},
+		{`*`, `"3"`, true, true},
+		{``, `"3"`, false, false},
+		{`3`, `"3"`, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchStrong(tt.header, tt.current); got != tt.strong {
+			t.Errorf("matchStrong(%q, %q) = %v, want %v", tt.header, tt.current, got, tt.strong)
+		}
+		if got := matchWeak(tt.header, tt.current); got != tt.weak {
+			t.Errorf("matchWeak(%q, %q) = %v, want %v", tt.header, tt.current, got, tt.weak)
+		}
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	an := &model.Announcement{Id: 10, Version: 3}
+
+	tests := []struct {
+		header string
+		ok     bool
+		status int
+	}{
+		{`"3"`, true, http.StatusOK},
+		{`"2", "3"`, true, http.StatusOK},
+		{`*`, true, http.StatusOK},
+		{``, false, http.StatusPreconditionRequired},
+		{`"2"`, false, http.StatusPreconditionFailed},
+		{`W/"3"`, false, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPatch, "/api/v1/announcements/10", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+		w := httptest.NewRecorder()
+
+		if ok := checkIfMatch(w, r, an); ok != tt.ok || w.Code != tt.status {
+			t.Errorf("If-Match %q: got %v with %d, want %v with %d", tt.header, ok, w.Code, tt.ok, tt.status)
+		}
+		if w.Code == http.StatusPreconditionFailed && w.Header().Get("ETag") != `"3"` {
+			t.Errorf("If-Match %q: 412 without the current ETag", tt.header)
+		}
+	}
+}
+
+func TestWriteFeedNotModified(t *testing.T) {
+	h := &handler{logger: logrus.New(), feedMaxAge: time.Minute}
+	page := []model.Announcement{{Id: 10, Article: "Продам старый диван", UpdatedAt: time.Date(2025, 7, 16, 22, 39, 54, 0, time.UTC)}}
+
+	w := httptest.NewRecorder()
+	h.writeFeed(w, httptest.NewRequest(http.MethodGet, "/api/v1/announcements", nil), page, 0)
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || tag == "" {
+		t.Fatalf("got %d with ETag %q, want 200 with a tag", w.Code, tag)
+	}
+
+	tests := []struct {
+		header string
+		status int
+	}{
+		{tag, http.StatusNotModified},
+		{tag[len("W/"):], http.StatusNotModified},
+		{`"other", ` + tag, http.StatusNotModified},
+		{`*`, http.StatusNotModified},
+		{`W/"other"`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/announcements", nil)
+		r.Header.Set("If-None-Match", tt.header)
+		w := httptest.NewRecorder()
+		h.writeFeed(w, r, page, 0)
+
+		if w.Code != tt.status {
+			t.Errorf("If-None-Match %q: got %d, want %d", tt.header, w.Code, tt.status)
+		}
+		if w.Code == http.StatusNotModified && w.Body.Len() > 0 {
+			t.Errorf("If-None-Match %q: 304 with a body", tt.header)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package announcements
+
+import (
+	"context"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/store"
+	"time"
+)
+
+const publishBatchSize = 100
+
+// Scheduler publishes drafts when their publish time comes. Drafts are locked
+// while being published, so running it on every instance of the service
+// publishes each draft once.
+type Scheduler struct {
+	db         store.PublishingStore
+	feed       store.FeedInvalidator
+	categories store.CategoriesStore
+	notifier   notifications.Notifier
+	logger     logger.Logger
+	interval   time.Duration
+	ttl        time.Duration
+}
+
+func NewScheduler(db store.PublishingStore, feed store.FeedInvalidator, categories store.CategoriesStore, notifier notifications.Notifier, logger logger.Logger, interval, ttl time.Duration) *Scheduler {
+	return &Scheduler{
+		db:         db,
+		feed:       feed,
+		categories: categories,
+		notifier:   notifier,
+		logger:     logger,
+		interval:   interval,
+		ttl:        ttl,
+	}
+}
+
+// Run publishes due drafts every interval until the context is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.publish()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) publish() {
+	for {
+		published, rejected, err := s.db.PublishDueDrafts(publishBatchSize, time.Now().Add(s.ttl), func(an model.Announcement) (bool, error) {
+			category, err := loadCategory(s.categories, an)
+			if err != nil {
+				return false, err
+			}
+			return publishable(an, category) == nil, nil
+		})
+		if err != nil {
+			s.logger.Info(err)
+			return
+		}
+
+		if len(published) > 0 {
+			s.feed.Invalidate()
+		}
+		for _, an := range published {
+			s.notify(an.UserId, "announcement.published", map[string]any{
+				"announcement_id": an.Id,
+				"title":           an.Article,
+			})
+		}
+		for _, an := range rejected {
+			s.notify(an.UserId, "announcement.publish_failed", map[string]any{
+				"announcement_id": an.Id,
+				"title":           an.Article,
+			})
+		}
+
+		if len(published)+len(rejected) < publishBatchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) notify(userId int64, kind string, payload any) {
+	if err := s.notifier.Notify(userId, kind, payload); err != nil {
+		s.logger.Info(err)
+	}
+}
@@ -0,0 +1,82 @@
+package announcements
+
+import (
+	"context"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/store"
+	"time"
+)
+
+// Sweeper archives expired announcements and warns the owners of the
+// announcements that are about to expire. Several instances of the service
+// may run it at the same time: the store hands every announcement out once.
+type Sweeper struct {
+	db       store.ExpirationStore
+	feed     store.FeedInvalidator
+	notifier notifications.Notifier
+	logger   logger.Logger
+	interval time.Duration
+	warning  time.Duration
+}
+
+func NewSweeper(db store.ExpirationStore, feed store.FeedInvalidator, notifier notifications.Notifier, logger logger.Logger, interval, warning time.Duration) *Sweeper {
+	return &Sweeper{
+		db:       db,
+		feed:     feed,
+		notifier: notifier,
+		logger:   logger,
+		interval: interval,
+		warning:  warning,
+	}
+}
+
+// Run sweeps every interval until the context is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sweeper) sweep() {
+	expiring, err := s.db.MarkExpiringAnnouncements(time.Now().Add(s.warning))
+	if err != nil {
+		s.logger.Info(err)
+	}
+	for _, an := range expiring {
+		s.notify(an.UserId, "announcement.expiring", map[string]any{
+			"announcement_id": an.Id,
+			"title":           an.Article,
+			"expires_at":      an.ExpiresAt,
+		})
+	}
+
+	expired, err := s.db.ArchiveExpiredAnnouncements()
+	if err != nil {
+		s.logger.Info(err)
+	}
+	if len(expired) > 0 {
+		s.feed.Invalidate()
+	}
+	for _, an := range expired {
+		s.notify(an.UserId, "announcement.expired", map[string]any{
+			"announcement_id": an.Id,
+			"title":           an.Article,
+		})
+	}
+}
+
+func (s *Sweeper) notify(userId int64, kind string, payload any) {
+	if err := s.notifier.Notify(userId, kind, payload); err != nil {
+		s.logger.Info(err)
+	}
+}
@@ -1,28 +1,23 @@
 package auth
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"marketplace-service/internal/logger"
 	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/problem"
 	"marketplace-service/internal/store"
 	"marketplace-service/internal/token"
+	"math"
 	"net/http"
-
-	"github.com/go-playground/validator/v10"
+	"strconv"
 )
 
-var validate *validator.Validate
-
-func init() {
-	validate = validator.New()
-}
-
 type handler struct {
-	db     store.UserStore
-	logger logger.Logger
-	token  *token.Service
+	db      store.UserStore
+	lockout *Lockout
+	logger  logger.Logger
+	token   *token.Service
 }
 
 type AuthRequest struct {
@@ -30,64 +25,81 @@ type AuthRequest struct {
 	Password string `json:"password" example:"StrongP@ssw0rd!" validate:"required,min=8,max=64"`
 }
 
-func NewHandler(db store.UserStore, l logger.Logger, token *token.Service) *handler {
+func NewHandler(db store.UserStore, lockout *Lockout, l logger.Logger, token *token.Service) *handler {
 	return &handler{
-		db:     db,
-		logger: l,
-		token:  token,
+		db:      db,
+		lockout: lockout,
+		logger:  l,
+		token:   token,
 	}
 }
 
 func (h *handler) RegisterService(mux *http.ServeMux) {
 	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
 
-	mux.Handle("POST /api/v1/auth", loggerMiddleware(http.HandlerFunc(h.authHandler)))
+	mux.Handle("POST /api/v1/auth", loggerMiddleware(middleware.BindBody[AuthRequest](h.authHandler)))
 }
 
 
 
 // Authorization of user
 // @Summary      Auth user
-// @Description  Auth user by username and password.
+// @Description  Auth user by username and password. Too many failed attempts for the username or from the address lock the login for a while, and the lock grows with every next failure.
 // @Tags         Users
 // @Accept       json
-// @Produce      json
+// @Produce      plain
 // @Param        request body AuthRequest true "User authorization details"
-// @Success      201 "User successfully authorized"
-// @Failure      400 "Invalid request payload or invalid username or invalid password"
-// @Failure      500 "Internal server error"
+// @Success      201 {string} string "User successfully authorized"
+// @Header       201 {string} Authorization "Bearer token of the user"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload or invalid username or invalid password"
+// @Failure      403 {object}  problem.Problem  "User is banned"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      429 {object}  problem.Problem  "Too many failed attempts, see the Retry-After header"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
 // @Router       /api/v1/auth [post]
 func (h *handler) authHandler(w http.ResponseWriter, r *http.Request) {
-	var userData AuthRequest
-	err := json.NewDecoder(r.Body).Decode(&userData)
+	userData := middleware.Body[AuthRequest](r)
+
+	ip := middleware.ClientIP(r)
 
+	retryAfter, err := h.lockout.RetryAfter(userData.Username, ip)
 	if err != nil {
-		h.logger.Error("Failed to decode request body: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-
-	// --- Валидация с помощью go-playground/validator ---
-	if err := validate.Struct(userData); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		problem.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
 		return
 	}
-	// --- Конец валидации ---
 
 	user, err := h.db.GetUserByCredentials(userData.Username, userData.Password)
 	if err != nil {
 		if errors.Is(err, store.ErrInvalidUsernameOrPassword) {
-			http.Error(w, "Invalid username or password", http.StatusBadRequest)
+			if err := h.lockout.Failure(userData.Username, ip); err != nil {
+				h.logger.Info(err)
+			}
+			problem.Error(w, "Invalid username or password", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, store.ErrUserBanned) {
+			problem.Error(w, "User is banned", http.StatusForbidden)
 			return
 		}
 
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	if err := h.lockout.Success(userData.Username); err != nil {
+		h.logger.Info(err)
+	}
+
 	token, _ := h.token.GenerateToken(fmt.Sprintf("%d", user.ID))
 
 	w.Header().Set("Authorization", "Bearer "+token)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte("User successfully authorized"))
 }
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"marketplace-service/internal/store"
+	"math"
+	"time"
+)
+
+// LockoutPolicy describes when the failed logins lock a key. After MaxFailures
+// failures within Window the key is locked for Lockout, and every next failure
+// doubles the lock up to MaxLockout.
+type LockoutPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	Lockout     time.Duration
+	MaxLockout  time.Duration
+}
+
+// Lockout protects the login from guessing passwords. The failures are counted
+// per username and per client address with their own policies.
+type Lockout struct {
+	attempts store.LoginAttemptsStore
+	user     LockoutPolicy
+	ip       LockoutPolicy
+}
+
+func NewLockout(attempts store.LoginAttemptsStore, user, ip LockoutPolicy) *Lockout {
+	return &Lockout{
+		attempts: attempts,
+		user:     user,
+		ip:       ip,
+	}
+}
+
+func userKey(username string) string {
+	return "user:" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// RetryAfter returns how long the login is locked for the username or the
+// address, zero if it is not.
+func (l *Lockout) RetryAfter(username, ip string) (time.Duration, error) {
+	until, err := l.attempts.LockedUntil(userKey(username), ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	return max(time.Until(until), 0), nil
+}
+
+// Failure counts the failed login and locks the username or the address when
+// they have failed too often.
+func (l *Lockout) Failure(username, ip string) error {
+	if err := l.fail(userKey(username), l.user); err != nil {
+		return err
+	}
+	return l.fail(ipKey(ip), l.ip)
+}
+
+func (l *Lockout) fail(key string, policy LockoutPolicy) error {
+	failures, err := l.attempts.RegisterFailure(key, policy.Window)
+	if err != nil || failures < policy.MaxFailures {
+		return err
+	}
+
+	return l.attempts.LockUntil(key, time.Now().Add(policy.lockout(failures)))
+}
+
+func (p LockoutPolicy) lockout(failures int) time.Duration {
+	exponent := failures - p.MaxFailures
+	if exponent > 30 {
+		return p.MaxLockout
+	}
+
+	lockout := p.Lockout * time.Duration(math.Pow(2, float64(exponent)))
+	if lockout <= 0 || lockout > p.MaxLockout {
+		return p.MaxLockout
+	}
+	return lockout
+}
+
+// Success forgets the failures of the username. The failures of the address
+// stay, so that logging in to an own account does not hide guessing others.
+func (l *Lockout) Success(username string) error {
+	return l.attempts.Reset(userKey(username))
+}
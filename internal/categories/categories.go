@@ -0,0 +1,47 @@
+package categories
+
+import (
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
+	"marketplace-service/internal/store"
+	"net/http"
+)
+
+type handler struct {
+	db     store.CategoriesStore
+	logger logger.Logger
+}
+
+func NewHandler(db store.CategoriesStore, logger logger.Logger) *handler {
+	return &handler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (h *handler) RegisterService(mux *http.ServeMux) {
+	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
+
+	mux.Handle("GET /api/v1/categories", loggerMiddleware(http.HandlerFunc(h.getCategories)))
+}
+
+// GetCategories gets categories with their attribute schemas
+// @Summary      Get categories
+// @Description  Get all categories with the attributes their announcements have. The attributes are used when creating announcements and as attr.* filters of the announcement list. This endpoint is public.
+// @Tags         Categories
+// @Produce      json
+// @Success      200  {array}  model.Category
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/categories [get]
+func (h *handler) getCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.db.GetCategories()
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, categories)
+}
@@ -0,0 +1,209 @@
+package categories
+
+import (
+	"bytes"
+	"encoding/json"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/validation"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FilterPrefix starts the query parameters that filter by attributes, e.g.
+// attr.mileage_max=150000.
+const FilterPrefix = "attr."
+
+type rangeValue struct {
+	From *int64 `json:"from"`
+	To   *int64 `json:"to"`
+}
+
+// ValidateAttributes checks the attributes against the schema of the category
+// and returns them in the form they are stored in. With partial set missing
+// required attributes are allowed, which is how drafts are saved.
+func ValidateAttributes(category *model.Category, raw map[string]json.RawMessage, partial bool) (json.RawMessage, error) {
+	attributes := make(map[string]any, len(raw))
+
+	for name, value := range raw {
+		spec, ok := category.Attribute(name)
+		if !ok {
+			return nil, validation.InField("attributes."+name, validation.Errorf("unknown_attribute", name, category.Slug))
+		}
+
+		v, err := decodeValue(spec, value)
+		if err != nil {
+			return nil, validation.InField("attributes."+name, err)
+		}
+		attributes[name] = v
+	}
+
+	if !partial {
+		for _, spec := range category.Attributes {
+			if _, ok := attributes[spec.Name]; spec.Required && !ok {
+				return nil, validation.InField("attributes."+spec.Name, validation.Errorf("attribute_required", spec.Name, category.Slug))
+			}
+		}
+	}
+
+	return json.Marshal(attributes)
+}
+
+// CheckAttributes validates the stored attributes of an announcement.
+func CheckAttributes(category *model.Category, stored json.RawMessage, partial bool) error {
+	raw := map[string]json.RawMessage{}
+	if len(stored) > 0 {
+		if err := json.Unmarshal(stored, &raw); err != nil {
+			return err
+		}
+	}
+
+	_, err := ValidateAttributes(category, raw, partial)
+	return err
+}
+
+func decodeValue(spec model.AttributeSpec, value json.RawMessage) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.DisallowUnknownFields()
+
+	switch spec.Type {
+	case model.AttributeInt:
+		var n int64
+		if err := decoder.Decode(&n); err != nil {
+			return nil, validation.Errorf("integer")
+		}
+		return n, checkBounds(spec, n)
+
+	case model.AttributeEnum:
+		var s string
+		if err := decoder.Decode(&s); err != nil {
+			return nil, validation.Errorf("string")
+		}
+		return s, checkEnum(spec, s)
+
+	case model.AttributeBool:
+		var b bool
+		if err := decoder.Decode(&b); err != nil {
+			return nil, validation.Errorf("boolean")
+		}
+		return b, nil
+
+	case model.AttributeRange:
+		var r rangeValue
+		if err := decoder.Decode(&r); err != nil || r.From == nil || r.To == nil {
+			return nil, validation.Errorf("range")
+		}
+		if *r.From > *r.To {
+			return nil, validation.Errorf("range_order")
+		}
+		if err := checkBounds(spec, *r.From); err != nil {
+			return nil, err
+		}
+		return r, checkBounds(spec, *r.To)
+	}
+
+	return nil, validation.Errorf("unsupported_type", spec.Type)
+}
+
+func checkBounds(spec model.AttributeSpec, n int64) error {
+	if spec.Min != nil && n < *spec.Min {
+		return validation.Errorf("at_least", *spec.Min)
+	}
+	if spec.Max != nil && n > *spec.Max {
+		return validation.Errorf("at_most", *spec.Max)
+	}
+	return nil
+}
+
+func checkEnum(spec model.AttributeSpec, s string) error {
+	if !slices.Contains(spec.Values, s) {
+		return validation.Errorf("one_of", strings.Join(spec.Values, ", "))
+	}
+	return nil
+}
+
+// ParseFilters turns the attr.* query parameters into attribute filters of the
+// category. Int attributes accept attr.X, attr.X_min and attr.X_max, range
+// attributes match the announcements whose range contains attr.X, enum and
+// bool attributes accept attr.X.
+func ParseFilters(category *model.Category, query url.Values) ([]store.AttributeFilter, error) {
+	var filters []store.AttributeFilter
+
+	for key, values := range query {
+		if !strings.HasPrefix(key, FilterPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, FilterPrefix)
+
+		if category == nil {
+			return nil, validation.InField(key, validation.Errorf("filter_requires_category", key))
+		}
+		if len(values) != 1 {
+			return nil, validation.InField(key, validation.Errorf("filter_repeated", key))
+		}
+
+		filter, err := parseFilter(category, name, values[0])
+		if err != nil {
+			return nil, validation.InField(key, err)
+		}
+		filters = append(filters, filter)
+	}
+
+	slices.SortFunc(filters, func(a, b store.AttributeFilter) int {
+		return strings.Compare(a.Name+a.Op, b.Name+b.Op)
+	})
+
+	return filters, nil
+}
+
+func parseFilter(category *model.Category, name, value string) (store.AttributeFilter, error) {
+	op := store.AttributeEquals
+	spec, ok := category.Attribute(name)
+	if !ok {
+		if base, found := strings.CutSuffix(name, "_min"); found {
+			spec, ok = category.Attribute(base)
+			op = store.AttributeMin
+		} else if base, found := strings.CutSuffix(name, "_max"); found {
+			spec, ok = category.Attribute(base)
+			op = store.AttributeMax
+		}
+		ok = ok && spec.Type == model.AttributeInt
+	}
+	if !ok {
+		return store.AttributeFilter{}, validation.Errorf("unknown_filter", category.Slug)
+	}
+
+	filter := store.AttributeFilter{Name: spec.Name, Op: op}
+
+	switch spec.Type {
+	case model.AttributeInt, model.AttributeRange:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, validation.Errorf("integer")
+		}
+		if spec.Type == model.AttributeRange {
+			filter.Op = store.AttributeContains
+		}
+		filter.Value = n
+
+	case model.AttributeEnum:
+		if err := checkEnum(spec, value); err != nil {
+			return filter, err
+		}
+		filter.Value = value
+
+	case model.AttributeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, validation.Errorf("true_or_false")
+		}
+		filter.Value = b
+
+	default:
+		return filter, validation.Errorf("unsupported_type", spec.Type)
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,300 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096
+
+	// catchUpBatch is how many missed messages are loaded from the store at once.
+	catchUpBatch = 200
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The connection is authorized by the bearer token and not by cookies,
+	// so a foreign page can not open it on behalf of the user.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ClientEvent is what the chat clients send over the socket.
+type ClientEvent struct {
+	Type           string `json:"type"`
+	ConversationId int64  `json:"conversation_id"`
+}
+
+type handler struct {
+	db     store.ConversationsStore
+	hub    Hub
+	logger logger.Logger
+	token  *token.Service
+}
+
+func NewHandler(db store.ConversationsStore, hub Hub, logger logger.Logger, token *token.Service) *handler {
+	return &handler{
+		db:     db,
+		hub:    hub,
+		logger: logger,
+		token:  token,
+	}
+}
+
+func (h *handler) RegisterService(mux *http.ServeMux) {
+	// Not wrapped into the logging middleware: its response writer can not be hijacked.
+	mux.HandleFunc("GET /api/v1/chat", h.serveChat)
+}
+
+// Chat socket
+// @Summary      Real-time chat
+// @Description  WebSocket delivering new messages, typing indicators and read receipts of all conversations of the authorized user.
+// @Description  Browsers can not set headers on WebSocket requests, so the token may also be passed in the access_token query parameter.
+// @Description  After a reconnect pass the id of the last received message in last_message_id to get the missed messages first.
+// @Description  Clients may send {"type": "typing" | "read", "conversation_id": 1}. New messages are sent with POST /api/v1/conversations/{id}/messages.
+// @Tags         Conversations
+// @Param        access_token     query   string  false  "JWT token, alternative to the Authorization header"
+// @Param        last_message_id  query   int     false  "Id of the last received message"
+// @Success      101  "Switching protocols"
+// @Failure      400 {object}  problem.Problem  "Invalid last_message_id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Router       /api/v1/chat [get]
+// @Security     Bearer
+func (h *handler) serveChat(w http.ResponseWriter, r *http.Request) {
+	userIdInt, ok := middleware.Authenticate(w, h.token, token.ExtractTokenOrQuery(r))
+	if !ok {
+		return
+	}
+	userId := int64(userIdInt)
+
+	var lastId int64 = -1
+	if lastIdString := r.URL.Query().Get("last_message_id"); lastIdString != "" {
+		var err error
+		lastId, err = strconv.ParseInt(lastIdString, 10, 64)
+		if err != nil || lastId < 0 {
+			problem.Error(w, "Invalid last_message_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the client.
+		return
+	}
+	defer conn.Close()
+
+	// Subscribe before catching up so nothing sent in between is lost.
+	live, unsubscribe := h.hub.Subscribe(userId)
+	defer unsubscribe()
+
+	c := &client{
+		handler: h,
+		conn:    conn,
+		userId:  userId,
+		lastId:  lastId,
+		replies: make(chan Event, 8),
+		done:    make(chan struct{}),
+		members: make(map[int64]*model.Conversation),
+	}
+
+	if lastId >= 0 {
+		if err := c.catchUp(); err != nil {
+			h.logger.Info(err)
+			return
+		}
+	}
+
+	go c.readLoop()
+	c.writeLoop(live)
+}
+
+// client serves one socket. Only writeLoop writes to the connection.
+type client struct {
+	handler *handler
+	conn    *websocket.Conn
+	userId  int64
+
+	// lastId is the id of the newest message sent to the client, -1 if the
+	// client did not ask for a catch up.
+	lastId int64
+
+	replies chan Event
+	done    chan struct{}
+
+	// members caches the conversations the user has been checked to take part in.
+	members map[int64]*model.Conversation
+}
+
+func (c *client) write(e Event) error {
+	if e.Type == EventMessage && e.Message != nil {
+		if e.Message.Id <= c.lastId {
+			return nil
+		}
+		c.lastId = e.Message.Id
+	}
+
+	e.Recipients = nil
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteJSON(e)
+}
+
+func (c *client) catchUp() error {
+	for {
+		missed, err := c.handler.db.GetMessagesAfter(c.userId, c.lastId, catchUpBatch)
+		if err != nil {
+			return err
+		}
+
+		for i := range missed {
+			m := missed[i]
+			e := Event{
+				Type:           EventMessage,
+				ConversationId: m.ConversationId,
+				UserId:         m.SenderId,
+				Message:        &m,
+				Date:           m.Date,
+			}
+			if err := c.write(e); err != nil {
+				return err
+			}
+		}
+
+		if len(missed) < catchUpBatch {
+			return nil
+		}
+	}
+}
+
+func (c *client) writeLoop(live <-chan Event) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+
+		case e := <-live:
+			if err := c.write(e); err != nil {
+				return
+			}
+
+		case e := <-c.replies:
+			if err := c.write(e); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *client) readLoop() {
+	defer close(c.done)
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var e ClientEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			c.reply("Invalid event")
+			continue
+		}
+
+		if err := c.handle(e); err != nil {
+			c.reply(err.Error())
+		}
+	}
+}
+
+func (c *client) reply(msg string) {
+	select {
+	case c.replies <- Event{Type: EventError, Error: msg, Date: time.Now()}:
+	default:
+	}
+}
+
+var errUnknownEvent = errors.New("unknown event type")
+var errConversationNotFound = errors.New("conversation not found")
+var errInternal = errors.New("internal server error")
+
+func (c *client) conversation(id int64) (*model.Conversation, error) {
+	if conversation, ok := c.members[id]; ok {
+		return conversation, nil
+	}
+
+	conversation, err := c.handler.db.GetConversationById(id, c.userId)
+	if err != nil {
+		if errors.Is(err, store.ErrConversationNotFound) {
+			return nil, errConversationNotFound
+		}
+		c.handler.logger.Info(err)
+		return nil, errInternal
+	}
+
+	if !conversation.Participant(c.userId) {
+		return nil, errConversationNotFound
+	}
+
+	c.members[id] = conversation
+	return conversation, nil
+}
+
+func (c *client) handle(e ClientEvent) error {
+	if e.Type != EventTyping && e.Type != EventRead {
+		return errUnknownEvent
+	}
+
+	conversation, err := c.conversation(e.ConversationId)
+	if err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case EventTyping:
+		err = c.handler.hub.Publish(NewTypingEvent(conversation, c.userId))
+
+	case EventRead:
+		var lastReadId int64
+		lastReadId, err = c.handler.db.MarkConversationRead(conversation.Id, c.userId)
+		if err == nil && lastReadId > 0 {
+			err = c.handler.hub.Publish(NewReadEvent(conversation, c.userId, lastReadId))
+		}
+	}
+
+	if err != nil {
+		c.handler.logger.Info(err)
+		return errInternal
+	}
+	return nil
+}
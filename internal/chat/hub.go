@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"sync"
+	"time"
+
+	"marketplace-service/internal/model"
+)
+
+const (
+	EventMessage = "message"
+	EventTyping  = "typing"
+	EventRead    = "read"
+	EventError   = "error"
+)
+
+// Event is what is sent to the chat clients.
+type Event struct {
+	Type           string         `json:"type"`
+	ConversationId int64          `json:"conversation_id,omitempty"`
+	UserId         int64          `json:"user_id,omitempty"`
+	Message        *model.Message `json:"message,omitempty"`
+	LastReadId     int64          `json:"last_read_id,omitempty"`
+	Date           time.Time      `json:"date"`
+	Error          string         `json:"error,omitempty"`
+
+	// Recipients are the users the event is delivered to.
+	Recipients []int64 `json:"recipients,omitempty"`
+}
+
+func NewMessageEvent(c *model.Conversation, m *model.Message) Event {
+	return Event{
+		Type:           EventMessage,
+		ConversationId: c.Id,
+		UserId:         m.SenderId,
+		Message:        m,
+		Date:           m.Date,
+		Recipients:     []int64{c.BuyerId, c.SellerId},
+	}
+}
+
+func NewTypingEvent(c *model.Conversation, userId int64) Event {
+	return Event{
+		Type:           EventTyping,
+		ConversationId: c.Id,
+		UserId:         userId,
+		Date:           time.Now(),
+		Recipients:     []int64{c.Interlocutor(userId)},
+	}
+}
+
+func NewReadEvent(c *model.Conversation, readerId, lastReadId int64) Event {
+	return Event{
+		Type:           EventRead,
+		ConversationId: c.Id,
+		UserId:         readerId,
+		LastReadId:     lastReadId,
+		Date:           time.Now(),
+		Recipients:     []int64{c.BuyerId, c.SellerId},
+	}
+}
+
+// Hub delivers chat events to the connections of their recipients.
+type Hub interface {
+	Publish(e Event) error
+	Subscribe(userId int64) (<-chan Event, func())
+}
+
+// subscriberBuffer is how many events a slow connection may lag behind
+// before new ones are dropped for it.
+const subscriberBuffer = 64
+
+// LocalHub delivers events to the connections served by this process only.
+type LocalHub struct {
+	mu          sync.RWMutex
+	subscribers map[int64]map[chan Event]struct{}
+}
+
+func NewLocalHub() *LocalHub {
+	return &LocalHub{
+		subscribers: make(map[int64]map[chan Event]struct{}),
+	}
+}
+
+func (h *LocalHub) Subscribe(userId int64) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userId] == nil {
+		h.subscribers[userId] = make(map[chan Event]struct{})
+	}
+	h.subscribers[userId][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		delete(h.subscribers[userId], ch)
+		if len(h.subscribers[userId]) == 0 {
+			delete(h.subscribers, userId)
+		}
+		h.mu.Unlock()
+	}
+
+	return ch, unsubscribe
+}
+
+func (h *LocalHub) Publish(e Event) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, userId := range e.Recipients {
+		for ch := range h.subscribers[userId] {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	}
+
+	return nil
+}
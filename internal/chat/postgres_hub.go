@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+
+	"marketplace-service/internal/logger"
+)
+
+const notifyChannel = "chat_events"
+
+// PostgresHub spreads events between several instances of the service with
+// Postgres LISTEN/NOTIFY. Every instance, the publishing one included,
+// receives the event back from Postgres and hands it to its LocalHub.
+type PostgresHub struct {
+	local    *LocalHub
+	db       *sql.DB
+	listener *pq.Listener
+	logger   logger.Logger
+}
+
+func NewPostgresHub(db *sql.DB, connInfo string, l logger.Logger) (*PostgresHub, error) {
+	listener := pq.NewListener(connInfo, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			l.Error("Chat listener: ", err)
+		}
+	})
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	h := &PostgresHub{
+		local:    NewLocalHub(),
+		db:       db,
+		listener: listener,
+		logger:   l,
+	}
+	go h.listen()
+
+	return h, nil
+}
+
+func (h *PostgresHub) Subscribe(userId int64) (<-chan Event, func()) {
+	return h.local.Subscribe(userId)
+}
+
+func (h *PostgresHub) Publish(e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.db.Exec(`SELECT pg_notify($1, $2)`, notifyChannel, string(payload))
+	return err
+}
+
+func (h *PostgresHub) Close() error {
+	return h.listener.Close()
+}
+
+func (h *PostgresHub) listen() {
+	for {
+		select {
+		case n, ok := <-h.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification means the connection was re-established.
+			// Events sent meanwhile are lost, clients catch up by message id.
+			if n == nil {
+				continue
+			}
+
+			var e Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				h.logger.Error("Chat listener: ", err)
+				continue
+			}
+			h.local.Publish(e)
+
+		case <-time.After(90 * time.Second):
+			go h.listener.Ping()
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"marketplace-service/internal/logger"
 	"sync"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -22,6 +24,94 @@ type Config struct {
 	}
 
 	Secret string `env:"JWT_SECRET"`
+
+	TrustedProxies []string `env:"TRUSTED_PROXIES" env-separator:"," env-description:"IPs or CIDR ranges of the proxies whose X-Forwarded-For is believed"`
+
+	RateLimit struct {
+		Store           string        `env:"RATE_LIMIT_STORE" env-default:"memory" env-description:"memory or postgres"`
+		Policies        string        `env:"RATE_LIMITS" env-default:"POST /api/v1/announcements=10/1m;GET /api/v1/announcements=120/1m;POST /api/v1/auth=30/1m;*=300/1m" env-description:"route=limit/period pairs separated by ;, * is the default policy"`
+		CleanupInterval time.Duration `env:"RATE_LIMIT_CLEANUP_INTERVAL" env-default:"5m" env-description:"How often the postgres store deletes refilled buckets"`
+	}
+
+	Chat struct {
+		Hub string `env:"CHAT_HUB" env-default:"local" env-description:"local or postgres"`
+	}
+
+	OpenAPI struct {
+		Validation string `env:"OPENAPI_VALIDATION" env-default:"off" env-description:"off, requests or responses; responses checks the answers too and is meant for test runs"`
+	}
+
+	Paging struct {
+		MaxLimit int `env:"MAX_PAGE_LIMIT" env-default:"100" env-description:"The largest page any list returns, at most 10000"`
+	}
+
+	Announcements struct {
+		TTL             time.Duration `env:"ANNOUNCEMENT_TTL" env-default:"720h"`
+		ExpiryWarning   time.Duration `env:"ANNOUNCEMENT_EXPIRY_WARNING" env-default:"72h"`
+		SweepInterval   time.Duration `env:"ANNOUNCEMENT_SWEEP_INTERVAL" env-default:"1m"`
+		PublishInterval time.Duration `env:"ANNOUNCEMENT_PUBLISH_INTERVAL" env-default:"30s"`
+		FeedMaxAge      time.Duration `env:"FEED_MAX_AGE" env-default:"30s" env-description:"How long shared caches may keep an anonymous feed page"`
+	}
+
+	FeedCache struct {
+		Backend string        `env:"FEED_CACHE" env-default:"memory" env-description:"memory or off"`
+		TTL     time.Duration `env:"FEED_CACHE_TTL" env-default:"30s"`
+		Size    int           `env:"FEED_CACHE_SIZE" env-default:"1000" env-description:"How many pages the memory cache holds"`
+	}
+
+	Idempotency struct {
+		TTL         time.Duration `env:"IDEMPOTENCY_TTL" env-default:"24h"`
+		LockTimeout time.Duration `env:"IDEMPOTENCY_LOCK_TIMEOUT" env-default:"1m"`
+		Wait        time.Duration `env:"IDEMPOTENCY_WAIT" env-default:"5s"`
+	}
+
+	Login struct {
+		Store           string        `env:"LOGIN_ATTEMPTS_STORE" env-default:"memory" env-description:"memory or postgres"`
+		MaxFailures     int           `env:"LOGIN_MAX_FAILURES" env-default:"5"`
+		IPMaxFailures   int           `env:"LOGIN_IP_MAX_FAILURES" env-default:"20"`
+		FailureWindow   time.Duration `env:"LOGIN_FAILURE_WINDOW" env-default:"15m"`
+		Lockout         time.Duration `env:"LOGIN_LOCKOUT" env-default:"1m"`
+		MaxLockout      time.Duration `env:"LOGIN_MAX_LOCKOUT" env-default:"1h"`
+		CleanupInterval time.Duration `env:"LOGIN_ATTEMPTS_CLEANUP_INTERVAL" env-default:"5m" env-description:"How often the memory store forgets expired failures"`
+	}
+
+	Reviews struct {
+		EditWindow time.Duration `env:"REVIEW_EDIT_WINDOW" env-default:"72h"`
+	}
+
+	Offers struct {
+		TTL time.Duration `env:"OFFER_TTL" env-default:"48h"`
+	}
+
+	Notifications struct {
+		Heartbeat time.Duration `env:"NOTIFICATIONS_HEARTBEAT" env-default:"15s"`
+	}
+}
+
+// check rejects the values the environment can hold but the service can not
+// run with, like the periods of the tickers, which panic unless positive.
+func (c *Config) check() error {
+	periods := []struct {
+		env   string
+		value time.Duration
+	}{
+		{"NOTIFICATIONS_HEARTBEAT", c.Notifications.Heartbeat},
+		{"ANNOUNCEMENT_SWEEP_INTERVAL", c.Announcements.SweepInterval},
+		{"ANNOUNCEMENT_PUBLISH_INTERVAL", c.Announcements.PublishInterval},
+		{"LOGIN_ATTEMPTS_CLEANUP_INTERVAL", c.Login.CleanupInterval},
+		{"RATE_LIMIT_CLEANUP_INTERVAL", c.RateLimit.CleanupInterval},
+	}
+	for _, p := range periods {
+		if p.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", p.env, p.value)
+		}
+	}
+
+	// The offset of the last page (100000) must fit into the integer.
+	if c.Paging.MaxLimit < 1 || c.Paging.MaxLimit > 10000 {
+		return fmt.Errorf("MAX_PAGE_LIMIT must be between 1 and 10000, got %d", c.Paging.MaxLimit)
+	}
+	return nil
 }
 
 var instance *Config
@@ -35,6 +125,9 @@ func GetConfig(l logger.Logger) *Config {
 			l.Info(help)
 			l.Fatal(err)
 		}
+		if err := instance.check(); err != nil {
+			l.Fatal(err)
+		}
 	})
 
 	return instance
@@ -0,0 +1,432 @@
+package conversations
+
+import (
+	"errors"
+	"marketplace-service/internal/chat"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"net/http"
+)
+
+type handler struct {
+	db       store.ConversationsStore
+	hub      chat.Hub
+	notifier notifications.Notifier
+	logger   logger.Logger
+	token    *token.Service
+	maxLimit int
+}
+
+type MessagePostRequest struct {
+	Text string `json:"text" example:"Здравствуйте! Диван ещё продается?" validate:"required,min=1,max=2000"`
+}
+
+type ConversationPostResponse struct {
+	Conversation model.Conversation `json:"conversation"`
+	Message      model.Message      `json:"message"`
+}
+
+type UnreadCountResponse struct {
+	UnreadCount int `json:"unread_count" example:"3"`
+}
+
+func NewHandler(db store.ConversationsStore, hub chat.Hub, notifier notifications.Notifier, logger logger.Logger, token *token.Service, maxLimit int) *handler {
+	return &handler{
+		db:       db,
+		hub:      hub,
+		notifier: notifier,
+		logger:   logger,
+		token:    token,
+		maxLimit: maxLimit,
+	}
+}
+
+func (h *handler) RegisterService(mux *http.ServeMux) {
+	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
+
+	withAuth := func(next http.HandlerFunc) http.Handler {
+		return loggerMiddleware(middleware.AuthMiddleware(h.token, next))
+	}
+	withAuthAndMessage := func(next http.HandlerFunc) http.Handler {
+		return withAuth(middleware.BindBody[MessagePostRequest](next))
+	}
+	withAuthAndPaging := func(next http.HandlerFunc) http.Handler {
+		return middleware.Chain(
+			middleware.AuthMiddleware(h.token, next),
+			middleware.BindQuery[middleware.PageQuery](middleware.AtMost("limit", float64(h.maxLimit))),
+			loggerMiddleware,
+		)
+	}
+
+	mux.Handle("POST /api/v1/announcements/{id}/conversations", withAuthAndMessage(h.startConversation))
+	mux.Handle("GET /api/v1/conversations", withAuthAndPaging(h.getConversations))
+	mux.Handle("GET /api/v1/conversations/inbox", withAuthAndPaging(h.getInbox))
+	mux.Handle("GET /api/v1/conversations/unread", withAuth(h.getUnreadCount))
+	mux.Handle("GET /api/v1/conversations/{id}/messages", withAuthAndPaging(h.getMessages))
+	mux.Handle("POST /api/v1/conversations/{id}/messages", withAuthAndMessage(h.sendMessage))
+	mux.Handle("POST /api/v1/conversations/{id}/read", withAuth(h.markRead))
+	mux.Handle("POST /api/v1/conversations/{id}/block", withAuth(h.blockInterlocutor))
+	mux.Handle("DELETE /api/v1/conversations/{id}/block", withAuth(h.unblockInterlocutor))
+}
+
+// loadConversation fetches the conversation from the path and makes sure the
+// current user takes part in it. It writes the error response itself.
+func (h *handler) loadConversation(w http.ResponseWriter, r *http.Request) (*model.Conversation, int64, bool) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid conversation id", http.StatusBadRequest)
+		return nil, 0, false
+	}
+
+	userIdInt, _ := middleware.GetUserId(r)
+	userId := int64(userIdInt)
+
+	conversation, err := h.db.GetConversationById(id, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrConversationNotFound) {
+			problem.Error(w, "Conversation not found", http.StatusNotFound)
+			return nil, 0, false
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, 0, false
+	}
+
+	if !conversation.Participant(userId) {
+		problem.Error(w, "Conversation not found", http.StatusNotFound)
+		return nil, 0, false
+	}
+
+	return conversation, userId, true
+}
+
+// send stores a message from the user unless one of the participants blocked the other.
+func (h *handler) send(w http.ResponseWriter, conversation *model.Conversation, senderId int64, text string) (*model.Message, bool) {
+	message := &model.Message{
+		ConversationId: conversation.Id,
+		SenderId:       senderId,
+		Text:           text,
+	}
+
+	if err := h.db.CreateMessage(message); err != nil {
+		if errors.Is(err, store.ErrUsersBlocked) {
+			problem.Error(w, "Messaging between these users is blocked", http.StatusForbidden)
+			return nil, false
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if err := h.hub.Publish(chat.NewMessageEvent(conversation, message)); err != nil {
+		h.logger.Info(err)
+	}
+
+	recipientId := conversation.Interlocutor(senderId)
+	err := h.notifier.Notify(recipientId, "message.new", map[string]any{
+		"conversation_id": conversation.Id,
+		"announcement_id": conversation.AnnouncementId,
+		"message_id":      message.Id,
+		"sender_id":       senderId,
+	})
+	if err != nil {
+		h.logger.Info(err)
+	}
+
+	return message, true
+}
+
+// Start conversation
+// @Summary      Contact the owner of an announcement
+// @Description  Start a conversation with the owner of the announcement, or continue the existing one, with a message.
+// @Tags         Conversations
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                 true  "Announcement id"
+// @Param        request  body  MessagePostRequest  true  "First message"
+// @Param        Idempotency-Key  header  string  false  "Repeating the request with the same key returns the first response"
+// @Success      201 {object} ConversationPostResponse "Message sent"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload or own announcement"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "Messaging between these users is blocked"
+// @Failure      404 {object}  problem.Problem  "Announcement not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id}/conversations [post]
+// @Security     Bearer
+func (h *handler) startConversation(w http.ResponseWriter, r *http.Request) {
+	announcementId, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	mpr := middleware.Body[MessagePostRequest](r)
+
+	userId, _ := middleware.GetUserId(r)
+
+	conversation, err := h.db.GetOrCreateConversation(announcementId, int64(userId))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAnnouncementNotFound):
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+		case errors.Is(err, store.ErrOwnAnnouncement):
+			problem.Error(w, "You can not start a conversation about your own announcement", http.StatusBadRequest)
+		case errors.Is(err, store.ErrUsersBlocked):
+			problem.Error(w, "Messaging between these users is blocked", http.StatusForbidden)
+		default:
+			h.logger.Info(err)
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	message, ok := h.send(w, conversation, int64(userId), mpr.Text)
+	if !ok {
+		return
+	}
+	conversation.LastMessageAt = message.Date
+
+	response.JSON(w, h.logger, http.StatusCreated, ConversationPostResponse{
+		Conversation: *conversation,
+		Message:      *message,
+	})
+}
+
+// GetConversations gets conversations of the current user
+// @Summary      Get conversations list
+// @Description  Get a paginated list of conversations the authorized user takes part in, most recently active first.
+// @Tags         Conversations
+// @Produce      json
+// @Param        query    query      middleware.PageQuery  false  "Page"
+// @Success      200      {array}    model.Conversation
+// @Failure      400 {object}  problem.Problem  "Invalid page or limit parameter"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations [get]
+// @Security     Bearer
+func (h *handler) getConversations(w http.ResponseWriter, r *http.Request) {
+	query := middleware.Query[middleware.PageQuery](r)
+
+	userId, _ := middleware.GetUserId(r)
+
+	conversations, err := h.db.GetConversationsByPage(int64(userId), query.Page, query.Limit)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, conversations)
+}
+
+// GetInbox gets the seller inbox
+// @Summary      Get seller inbox
+// @Description  Get conversations about the announcements of the authorized user grouped by announcement.
+// @Description  The page and limit count announcements, each one comes with all of its conversations.
+// @Tags         Conversations
+// @Produce      json
+// @Param        query    query      middleware.PageQuery  false  "Page"
+// @Success      200      {array}    model.ListingInbox
+// @Failure      400 {object}  problem.Problem  "Invalid page or limit parameter"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations/inbox [get]
+// @Security     Bearer
+func (h *handler) getInbox(w http.ResponseWriter, r *http.Request) {
+	query := middleware.Query[middleware.PageQuery](r)
+
+	userId, _ := middleware.GetUserId(r)
+
+	inbox, err := h.db.GetSellerInbox(int64(userId), query.Page, query.Limit)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, inbox)
+}
+
+// GetUnreadCount gets the number of unread messages
+// @Summary      Get unread messages counter
+// @Description  Get the number of unread messages in all conversations of the authorized user.
+// @Tags         Conversations
+// @Produce      json
+// @Success      200      {object}   UnreadCountResponse
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations/unread [get]
+// @Security     Bearer
+func (h *handler) getUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userId, _ := middleware.GetUserId(r)
+
+	count, err := h.db.CountUnreadMessages(int64(userId))
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, UnreadCountResponse{UnreadCount: count})
+}
+
+// GetMessages gets messages of a conversation
+// @Summary      Get messages
+// @Description  Get a paginated list of messages of the conversation, newest first.
+// @Tags         Conversations
+// @Produce      json
+// @Param        id       path       int    true   "Conversation id"
+// @Param        query    query      middleware.PageQuery  false  "Page"
+// @Success      200      {array}    model.Message
+// @Failure      400 {object}  problem.Problem  "Invalid conversation id, page or limit parameter"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      404 {object}  problem.Problem  "Conversation not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations/{id}/messages [get]
+// @Security     Bearer
+func (h *handler) getMessages(w http.ResponseWriter, r *http.Request) {
+	conversation, _, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	query := middleware.Query[middleware.PageQuery](r)
+
+	messages, err := h.db.GetMessagesByPage(conversation.Id, query.Page, query.Limit)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, messages)
+}
+
+// Send message
+// @Summary      Send a message
+// @Description  Send a message to the other participant of the conversation.
+// @Tags         Conversations
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                 true  "Conversation id"
+// @Param        request  body  MessagePostRequest  true  "Message"
+// @Param        Idempotency-Key  header  string  false  "Repeating the request with the same key returns the first response"
+// @Success      201 {object} model.Message "Message sent"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "Messaging between these users is blocked"
+// @Failure      404 {object}  problem.Problem  "Conversation not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations/{id}/messages [post]
+// @Security     Bearer
+func (h *handler) sendMessage(w http.ResponseWriter, r *http.Request) {
+	conversation, userId, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	mpr := middleware.Body[MessagePostRequest](r)
+
+	message, ok := h.send(w, conversation, userId, mpr.Text)
+	if !ok {
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusCreated, message)
+}
+
+// Mark conversation as read
+// @Summary      Mark conversation as read
+// @Description  Mark all messages received by the authorized user in the conversation as read.
+// @Tags         Conversations
+// @Param        id   path      int  true  "Conversation id"
+// @Success      204  "Conversation marked as read"
+// @Failure      400 {object}  problem.Problem  "Invalid conversation id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      404 {object}  problem.Problem  "Conversation not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations/{id}/read [post]
+// @Security     Bearer
+func (h *handler) markRead(w http.ResponseWriter, r *http.Request) {
+	conversation, userId, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	lastReadId, err := h.db.MarkConversationRead(conversation.Id, userId)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if lastReadId > 0 {
+		if err := h.hub.Publish(chat.NewReadEvent(conversation, userId, lastReadId)); err != nil {
+			h.logger.Info(err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Block interlocutor
+// @Summary      Block the other participant
+// @Description  Block the other participant of the conversation. Messages between blocked users are rejected in both directions.
+// @Tags         Conversations
+// @Param        id   path      int  true  "Conversation id"
+// @Success      204  "User blocked"
+// @Failure      400 {object}  problem.Problem  "Invalid conversation id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      404 {object}  problem.Problem  "Conversation not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations/{id}/block [post]
+// @Security     Bearer
+func (h *handler) blockInterlocutor(w http.ResponseWriter, r *http.Request) {
+	conversation, userId, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.BlockUser(userId, conversation.Interlocutor(userId)); err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unblock interlocutor
+// @Summary      Unblock the other participant
+// @Description  Remove the block the authorized user has put on the other participant of the conversation.
+// @Tags         Conversations
+// @Param        id   path      int  true  "Conversation id"
+// @Success      204  "User unblocked"
+// @Failure      400 {object}  problem.Problem  "Invalid conversation id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      404 {object}  problem.Problem  "Conversation not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/conversations/{id}/block [delete]
+// @Security     Bearer
+func (h *handler) unblockInterlocutor(w http.ResponseWriter, r *http.Request) {
+	conversation, userId, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.UnblockUser(userId, conversation.Interlocutor(userId)); err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
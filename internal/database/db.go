@@ -12,14 +12,18 @@ import (
 
 
 
-func ConnectToDatabase(cfg *config.Config, l logger.Logger) (*sql.DB, error) {
-    databaseInfo := fmt.Sprintf("host=%v port=%v user=%v password=%v dbname=%v sslmode=disable",
+func ConnectionInfo(cfg *config.Config) string {
+    return fmt.Sprintf("host=%v port=%v user=%v password=%v dbname=%v sslmode=disable",
         cfg.Postgres.Host,
         cfg.Postgres.Port,
         cfg.Postgres.User,
         cfg.Postgres.Password,
         cfg.Postgres.DBName,
     )
+}
+
+func ConnectToDatabase(cfg *config.Config, l logger.Logger) (*sql.DB, error) {
+    databaseInfo := ConnectionInfo(cfg)
     
 	database, err := sql.Open("postgres", databaseInfo)
     
@@ -33,3 +37,10 @@ func ConnectToDatabase(cfg *config.Config, l logger.Logger) (*sql.DB, error) {
 func CloseConnection(db *sql.DB) {
 	db.Close()
 }
+
+// Migrate runs the schema on the database. The schema only creates what is
+// missing, so it can be run on every release.
+func Migrate(db *sql.DB, schema string) error {
+	_, err := db.Exec(schema)
+	return err
+}
@@ -5,48 +5,70 @@ import (
 	"net/http"
 	"strconv"
 
+	"marketplace-service/internal/problem"
 	"marketplace-service/internal/token"
 )
 
-func isAuthorized(tok *token.Service, r *http.Request) bool {
-	tokenStr := token.ExtractToken(r)
-	_, err := tok.ValidateToken(tokenStr)
-	return err == nil
+// Authenticate returns the user of the token, checking that the user is not
+// banned. It writes the error response itself. The ban is looked up in the
+// database, so it is called once per request or stream.
+func Authenticate(w http.ResponseWriter, tok *token.Service, tokenStr string) (int, bool) {
+	subject, err := tok.ValidateToken(tokenStr)
+	if err != nil {
+		problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	banned, err := tok.Banned(subject)
+	if err != nil {
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return 0, false
+	}
+	if banned {
+		problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	userId, _ := strconv.Atoi(subject)
+	return userId, true
 }
 
 func AuthMiddleware(tok *token.Service, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !isAuthorized(tok, r) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		userId, ok := Authenticate(w, tok, token.ExtractToken(r))
+		if !ok {
 			return
 		}
 
-		tokenStr := token.ExtractToken(r)
-		tokenValid, _ := tok.ValidateToken(tokenStr)
-		token, _ := strconv.Atoi(tokenValid)
-
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, "token", token)
-
+		ctx := context.WithValue(r.Context(), UserIdKey, userId)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
+// GetUserId returns the id of the user authenticated by AuthMiddleware or
+// OptionalAuthMiddleware.
+func GetUserId(r *http.Request) (int, bool) {
+	userId, ok := r.Context().Value(UserIdKey).(int)
+	return userId, ok
+}
+
+// OptionalAuthMiddleware lets anonymous requests through and authenticates
+// the ones with the Authorization header.
 func OptionalAuthMiddleware(tok *token.Service) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := r.Header.Get("Authorization")
-			if token == "" {
+			if r.Header.Get("Authorization") == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			if !isAuthorized(tok, r) {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			userId, ok := Authenticate(w, tok, token.ExtractToken(r))
+			if !ok {
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), UserIdKey, userId)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
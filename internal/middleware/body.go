@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/validation"
+	"net/http"
+	"strings"
+)
+
+// MaxBodyBytes limits the size of the bodies bound by BindBody.
+const MaxBodyBytes = 64 << 10
+
+// bodyKey keeps the bound body of the type T in the context.
+type bodyKey[T any] struct{}
+
+// Validatable is implemented by the bodies whose rules depend on their own
+// values. BindBody calls Validate instead of checking the validate tags.
+type Validatable interface {
+	Validate() error
+}
+
+// BindBody decodes the JSON body into T and validates it before calling next,
+// which gets the body with Body. Unknown fields, trailing data and bodies over
+// MaxBodyBytes are refused.
+func BindBody[T any](next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body T
+		if err := decodeBody(w, r, &body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			validation.WriteError(w, r, err)
+			return
+		}
+
+		var err error
+		if v, ok := any(body).(Validatable); ok {
+			err = v.Validate()
+		} else {
+			err = validation.Struct(body)
+		}
+		if err != nil {
+			validation.WriteError(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), bodyKey[T]{}, body)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// Body returns the body bound by BindBody.
+func Body[T any](r *http.Request) T {
+	body, _ := r.Context().Value(bodyKey[T]{}).(T)
+	return body
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &tooLarge):
+			return err
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return validation.InField(typeErr.Field, validation.Errorf("wrong_type", typeErr.Type.String()))
+		}
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return validation.InField(strings.Trim(field, `"`), validation.Errorf("unknown_field"))
+		}
+		return validation.Errorf("invalid_json")
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		return validation.Errorf("invalid_json")
+	}
+	return nil
+}
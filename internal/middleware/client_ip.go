@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	trustedProxiesMu sync.RWMutex
+	trustedProxies   []*net.IPNet
+)
+
+// SetTrustedProxies sets the addresses of the proxies whose X-Forwarded-For
+// is believed. The addresses are IPs or CIDR ranges.
+func SetTrustedProxies(addresses []string) error {
+	var nets []*net.IPNet
+
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if !strings.Contains(address, "/") {
+			if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+				address += "/32"
+			} else {
+				address += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(address)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", address, err)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	trustedProxiesMu.Lock()
+	trustedProxies = nets
+	trustedProxiesMu.Unlock()
+	return nil
+}
+
+func isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent the request. When the
+// request comes from a trusted proxy, X-Forwarded-For is read from the right
+// and the first address that is not a trusted proxy is the client.
+func ClientIP(r *http.Request) string {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(client) {
+		return client
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(address) == nil {
+			break
+		}
+		client = address
+		if !isTrustedProxy(address) {
+			break
+		}
+	}
+
+	return client
+}
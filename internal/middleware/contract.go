@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/problem"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// Contract checks the requests against the OpenAPI document of the service,
+// and the responses too when asked. Checking the responses buffers them and
+// is meant for test runs, where a response that drifted from the document is
+// replaced with 500.
+type Contract struct {
+	router    routers.Router
+	logger    logger.Logger
+	responses bool
+}
+
+// NewContract makes the middleware of the document.
+func NewContract(doc *openapi3.T, logger logger.Logger, responses bool) (*Contract, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Contract{
+		router:    router,
+		logger:    logger,
+		responses: responses,
+	}, nil
+}
+
+// Middleware checks the requests of the routes described by the document,
+// the other ones, like the Swagger UI, pass unchecked. Security is left to
+// the handlers.
+func (c *Contract) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, params, err := c.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				MultiError:         true,
+				// BindQuery tells given parameters from defaults.
+				SkipSettingDefaults: true,
+			},
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			problem.Write(w, requestProblem(err))
+			return
+		}
+
+		if !c.responses || streams(r, route.Operation) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &bufferedResponseWriter{header: w.Header().Clone()}
+		next.ServeHTTP(rw, r)
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rw.status,
+			Header:                 rw.header,
+			Body:                   io.NopCloser(bytes.NewReader(rw.body.Bytes())),
+			Options:                &openapi3filter.Options{MultiError: true},
+		})
+		if err != nil {
+			c.logger.Error(r.Method, " ", route.Path, ": response does not match the API description: ", err)
+			problem.Error(w, "Response does not match the API description: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for key, values := range rw.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rw.status)
+		if _, err := w.Write(rw.body.Bytes()); err != nil {
+			c.logger.Info(err)
+		}
+	})
+}
+
+// bufferedResponseWriter holds the response until it has been checked.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *bufferedResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *bufferedResponseWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+}
+
+func (rw *bufferedResponseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	return rw.body.Write(b)
+}
+
+// streams reports whether the response is a stream that cannot be buffered:
+// a WebSocket or Server-Sent Events, asked for or documented.
+func streams(r *http.Request, op *openapi3.Operation) bool {
+	if r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return true
+	}
+	for _, response := range op.Responses.Map() {
+		if response.Value != nil && response.Value.Content.Get("text/event-stream") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// requestProblem lists the parameters and the fields of the body that do not
+// match the document.
+func requestProblem(err error) *problem.Problem {
+	p := problem.New(http.StatusBadRequest, "The request does not match the API description")
+	p.Type = problem.TypeValidation
+
+	var errs openapi3.MultiError
+	if !errors.As(err, &errs) {
+		errs = openapi3.MultiError{err}
+	}
+
+	for _, err := range errs {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(err, &requestErr) {
+			p.Errors = append(p.Errors, problem.FieldError{Rule: "openapi", Message: err.Error()})
+			continue
+		}
+
+		field := ""
+		if requestErr.Parameter != nil {
+			field = requestErr.Parameter.Name
+		}
+
+		var schemaErrs openapi3.MultiError
+		if !errors.As(requestErr.Err, &schemaErrs) {
+			schemaErrs = openapi3.MultiError{requestErr.Err}
+		}
+		for _, err := range schemaErrs {
+			p.Errors = append(p.Errors, fieldProblem(field, requestErr, err))
+		}
+	}
+
+	return p
+}
+
+func fieldProblem(field string, requestErr *openapi3filter.RequestError, err error) problem.FieldError {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			field = strings.Join(append(strings.Fields(field), pointer...), ".")
+		}
+		return problem.FieldError{Field: field, Rule: schemaErr.SchemaField, Message: schemaErr.Reason}
+	}
+
+	message := requestErr.Reason
+	if err != nil {
+		message = err.Error()
+	}
+	return problem.FieldError{Field: field, Rule: "openapi", Message: message}
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKey    = 255
+	maxIdempotentBody    = 1 << 20
+	idempotencyPoll      = 100 * time.Millisecond
+)
+
+// Idempotency makes the retries of create requests with the same
+// Idempotency-Key replay the first response instead of creating again.
+type Idempotency struct {
+	store       store.IdempotencyStore
+	token       *token.Service
+	logger      logger.Logger
+	ttl         time.Duration
+	lockTimeout time.Duration
+	wait        time.Duration
+}
+
+// NewIdempotency keeps the responses for ttl. A duplicate that comes while the
+// first request is still running waits for it up to wait, and a request that
+// has not completed within lockTimeout is considered lost.
+func NewIdempotency(store store.IdempotencyStore, token *token.Service, logger logger.Logger, ttl, lockTimeout, wait time.Duration) *Idempotency {
+	return &Idempotency{
+		store:       store,
+		token:       token,
+		logger:      logger,
+		ttl:         ttl,
+		lockTimeout: lockTimeout,
+		wait:        wait,
+	}
+}
+
+// Routes makes the routes of the mux with the given patterns idempotent.
+func (i *Idempotency) Routes(mux *http.ServeMux, patterns ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		idempotent := i.Middleware(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, pattern := mux.Handler(r); slices.Contains(patterns, pattern) {
+				idempotent.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Middleware makes the handler idempotent for authorized requests with an
+// Idempotency-Key. Other requests are passed as they are.
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		userIdString, err := i.token.ValidateToken(token.ExtractToken(r))
+		if key == "" || err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userId, err := strconv.ParseInt(userIdString, 10, 64)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKey {
+			problem.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+		if err != nil {
+			problem.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxIdempotentBody {
+			problem.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := fingerprint(r, body)
+
+		request, err := i.reserve(userId, key, fingerprint)
+		if err != nil {
+			if errors.Is(err, errIdempotencyInProgress) {
+				problem.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+				return
+			}
+			i.logger.Info(err)
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if request != nil {
+			if request.Fingerprint != fingerprint {
+				problem.Error(w, "Idempotency-Key was used with another request", http.StatusUnprocessableEntity)
+				return
+			}
+			replay(w, request)
+			return
+		}
+
+		recorder := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status >= http.StatusInternalServerError {
+			if err := i.store.Release(userId, key); err != nil {
+				i.logger.Info(err)
+			}
+			return
+		}
+
+		err = i.store.Complete(userId, key, &model.IdempotentRequest{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      recorder.status,
+			Header:      recorder.header,
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			i.logger.Info(err)
+		}
+	})
+}
+
+var errIdempotencyInProgress = errors.New("idempotent request in progress")
+
+// reserve claims the key or returns the completed request stored under it.
+// While the request under the key is running it waits for it.
+func (i *Idempotency) reserve(userId int64, key, fingerprint string) (*model.IdempotentRequest, error) {
+	deadline := time.Now().Add(i.wait)
+
+	for {
+		reserved, request, err := i.store.Reserve(userId, key, fingerprint, i.ttl, i.lockTimeout)
+		if err != nil || reserved {
+			return nil, err
+		}
+		if request.Completed || request.Fingerprint != fingerprint {
+			return request, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errIdempotencyInProgress
+		}
+		time.Sleep(idempotencyPoll)
+	}
+}
+
+// fingerprint identifies the request by its method, path and body, so that a
+// key used for another request is noticed.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, request *model.IdempotentRequest) {
+	for name, values := range request.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(request.Status)
+	w.Write(request.Body)
+}
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.status = code
+		rw.header = storedHeader(rw.ResponseWriter.Header())
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// storedHeader copies the headers of the response without the ones that
+// describe the current request only.
+func storedHeader(header http.Header) http.Header {
+	stored := header.Clone()
+	for name := range stored {
+		if strings.HasPrefix(name, "Ratelimit-") || name == "Retry-After" || name == "Date" {
+			stored.Del(name)
+		}
+	}
+	return stored
+}
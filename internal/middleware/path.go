@@ -0,0 +1,13 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// PathId returns the {id} of the path. Ids that are not positive integers are
+// not valid.
+func PathId(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	return id, err == nil && id > 0
+}
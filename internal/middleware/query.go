@@ -0,0 +1,369 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"marketplace-service/internal/validation"
+)
+
+// A query schema is a struct whose fields are the query parameters. The tags
+// are the ones swag reads, so the documentation of the parameters is
+// generated from the same definition with
+//
+//	@Param  query  query  FeedQuery  false  "Filters"
+//
+// The tags are:
+//
+//	form              name of the parameter, fields without it are skipped
+//	default           value used when the parameter is absent or empty
+//	defaultItems      the same for slices, which swag cannot show, so the doc
+//	                  comment of the field should mention it
+//	validate          "required" when the parameter must be set
+//	minimum, maximum  bounds of numbers
+//	maxLength         the longest string in characters
+//	enums             allowed values of strings, of the items for slices
+//	format            "date" for dates without the time, dates are RFC 3339 otherwise
+//	collectionFormat  "csv" (the default) or "multi" for repeated parameters
+//	maxItems          the most items of a slice, not shown by swag
+//	uniqueItems       "true" refuses repeated items, not shown by swag
+//
+// Fields are strings, bools, integers, floats, time.Time, pointers to them,
+// which are nil when the parameter is absent, and slices of them. Embedded
+// structs add their fields.
+//
+// Checks of several parameters together, such as Ordered or Together, are the
+// QueryRules given to BindQuery.
+
+// PageQuery is the page of a list, the schemas of the lists with filters
+// embed it.
+type PageQuery struct {
+	// Page number for pagination, starts from 1, at most 100000
+	Page int `form:"page" default:"1" minimum:"1" maximum:"100000"`
+	// Number of items per page, at most MAX_PAGE_LIMIT (100 by default)
+	Limit int `form:"limit" default:"10" minimum:"1"`
+}
+
+// queryKey keeps the bound query of the type T in the context.
+type queryKey[T any] struct{}
+
+// BindQuery parses the query into T and checks the rules before calling next,
+// which gets it with Query. The schema is checked when the middleware is made,
+// so a wrong tag or rule stops the service at the start.
+func BindQuery[T any](rules ...QueryRule) func(http.Handler) http.Handler {
+	t := reflect.TypeFor[T]()
+	schema := mustSchema(t)
+	schema.mustRules(t, rules)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var q T
+			if err := schema.decode(r.URL.Query(), reflect.ValueOf(&q).Elem(), rules); err != nil {
+				validation.WriteError(w, r, err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), queryKey[T]{}, q)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Query returns the query bound by BindQuery.
+func Query[T any](r *http.Request) T {
+	q, _ := r.Context().Value(queryKey[T]{}).(T)
+	return q
+}
+
+type queryParam struct {
+	index       []int
+	name        string
+	defaults    []string
+	required    bool
+	min, max    *float64
+	maxLength   int
+	enums       []string
+	date        bool
+	multi       bool
+	maxItems    int
+	uniqueItems bool
+}
+
+type querySchema []queryParam
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func mustSchema(t reflect.Type) querySchema {
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("query schema %s is not a struct", t))
+	}
+
+	var schema querySchema
+	for _, field := range reflect.VisibleFields(t) {
+		name := field.Tag.Get("form")
+		if name == "" || !field.IsExported() {
+			continue
+		}
+
+		p := queryParam{
+			index:       field.Index,
+			name:        name,
+			required:    slices.Contains(strings.Split(field.Tag.Get("validate"), ","), "required"),
+			enums:       splitList(field.Tag.Get("enums")),
+			date:        field.Tag.Get("format") == "date",
+			multi:       field.Tag.Get("collectionFormat") == "multi",
+			uniqueItems: field.Tag.Get("uniqueItems") == "true",
+			min:         mustFloatTag(field, "minimum"),
+			max:         mustFloatTag(field, "maximum"),
+			maxLength:   mustIntTag(field, "maxLength"),
+			maxItems:    mustIntTag(field, "maxItems"),
+		}
+
+		elem := field.Type
+		if elem.Kind() == reflect.Slice {
+			elem = elem.Elem()
+		} else if elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if !supported(elem) {
+			panic(fmt.Sprintf("query parameter %s of %s has unsupported type %s", name, t, field.Type))
+		}
+
+		defaultTag := "default"
+		if field.Type.Kind() == reflect.Slice {
+			defaultTag = "defaultItems"
+		}
+		if value, ok := field.Tag.Lookup(defaultTag); ok {
+			p.defaults = p.split(field.Type, []string{value})
+			for _, raw := range p.defaults {
+				if _, err := p.parse(elem, raw); err != nil {
+					panic(fmt.Sprintf("default of query parameter %s of %s: %v", name, t, err))
+				}
+			}
+		}
+
+		schema = append(schema, p)
+	}
+
+	return schema
+}
+
+func supported(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func mustFloatTag(field reflect.StructField, tag string) *float64 {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		panic(fmt.Sprintf("%s of field %s: %v", tag, field.Name, err))
+	}
+	return &f
+}
+
+func mustIntTag(field reflect.StructField, tag string) int {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("%s of field %s: %v", tag, field.Name, err))
+	}
+	return n
+}
+
+// decode parses every parameter and reports all the invalid ones. The rules
+// run only when the parameters are valid.
+func (s querySchema) decode(values url.Values, dst reflect.Value, rules []QueryRule) error {
+	q := boundQuery{
+		schema: s,
+		value:  dst,
+		given:  map[string]bool{},
+		raws:   map[string][]string{},
+	}
+
+	var errs []error
+	for _, p := range s {
+		field := dst.FieldByIndex(p.index)
+
+		raws := p.split(field.Type(), values[p.name])
+		if len(raws) > 0 {
+			q.given[p.name] = true
+		} else if p.required {
+			errs = append(errs, validation.InField(p.name, validation.Errorf("required_param")))
+			continue
+		} else {
+			raws = p.defaults
+		}
+		if len(raws) == 0 {
+			continue
+		}
+		q.raws[p.name] = raws
+
+		if err := p.set(field, raws); err != nil {
+			errs = append(errs, validation.InField(p.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return validation.Join(errs...)
+	}
+
+	for _, rule := range rules {
+		errs = append(errs, rule.check(q)...)
+	}
+	return validation.Join(errs...)
+}
+
+// split gives the raw values of the parameter. Empty values count as absent.
+func (p queryParam) split(t reflect.Type, values []string) []string {
+	var raws []string
+	for _, value := range values {
+		if t.Kind() == reflect.Slice && !p.multi {
+			raws = append(raws, strings.Split(value, ",")...)
+		} else {
+			raws = append(raws, value)
+		}
+	}
+
+	return slices.DeleteFunc(raws, func(raw string) bool {
+		return strings.TrimSpace(raw) == ""
+	})
+}
+
+func (p queryParam) set(field reflect.Value, raws []string) error {
+	switch field.Kind() {
+	case reflect.Slice:
+		if p.maxItems > 0 && len(raws) > p.maxItems {
+			return validation.Errorf("too_many_values", p.maxItems)
+		}
+
+		items := reflect.MakeSlice(field.Type(), 0, len(raws))
+		for i, raw := range raws {
+			if p.uniqueItems && slices.Contains(raws[:i], raw) {
+				return validation.Errorf("repeated_value", raw)
+			}
+			item, err := p.parse(field.Type().Elem(), raw)
+			if err != nil {
+				return err
+			}
+			items = reflect.Append(items, item)
+		}
+		field.Set(items)
+
+	case reflect.Pointer:
+		item, err := p.parse(field.Type().Elem(), raws[0])
+		if err != nil {
+			return err
+		}
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().Set(item)
+		field.Set(ptr)
+
+	default:
+		item, err := p.parse(field.Type(), raws[0])
+		if err != nil {
+			return err
+		}
+		field.Set(item)
+	}
+	return nil
+}
+
+func (p queryParam) parse(t reflect.Type, raw string) (reflect.Value, error) {
+	raw = strings.TrimSpace(raw)
+	v := reflect.New(t).Elem()
+
+	if t == timeType {
+		layout := time.RFC3339
+		if p.date {
+			layout = time.DateOnly
+		}
+		at, err := time.Parse(layout, raw)
+		if err != nil {
+			return v, validation.Errorf("date", layout)
+		}
+		v.Set(reflect.ValueOf(at))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if len(p.enums) > 0 && !slices.Contains(p.enums, raw) {
+			return v, validation.Errorf("one_of", strings.Join(p.enums, ", "))
+		}
+		if p.maxLength > 0 && utf8.RuneCountInString(raw) > p.maxLength {
+			return v, validation.Errorf("too_long", p.maxLength)
+		}
+		v.SetString(raw)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return v, validation.Errorf("true_or_false")
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return v, validation.Errorf("integer")
+		}
+		if err := p.checkBounds(float64(n)); err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return v, validation.Errorf("number")
+		}
+		if err := p.checkBounds(f); err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+	}
+
+	return v, nil
+}
+
+func (p queryParam) checkBounds(f float64) error {
+	switch {
+	case p.min != nil && p.max != nil && (f < *p.min || f > *p.max):
+		return validation.Errorf("between", *p.min, *p.max)
+	case p.min != nil && f < *p.min:
+		return validation.Errorf("at_least", *p.min)
+	case p.max != nil && f > *p.max:
+		return validation.Errorf("at_most", *p.max)
+	}
+	return nil
+}
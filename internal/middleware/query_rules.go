@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"marketplace-service/internal/validation"
+)
+
+// QueryRule checks several parameters of a query together. Rules run after
+// every parameter has been parsed and report each parameter they refuse, so
+// a client sees all of them at once. The names of the parameters are checked
+// against the schema by BindQuery.
+type QueryRule struct {
+	params []string
+	check  func(q boundQuery) []error
+}
+
+// boundQuery is the parsed query as the rules see it.
+type boundQuery struct {
+	schema querySchema
+	value  reflect.Value
+	// given holds the parameters set by the client, defaults aside.
+	given map[string]bool
+	// raws are the values of the parameters, defaults included.
+	raws map[string][]string
+}
+
+// field returns the value of the parameter, following pointers. It is
+// invalid for nil pointers.
+func (q boundQuery) field(name string) reflect.Value {
+	for _, p := range q.schema {
+		if p.name == name {
+			return reflect.Indirect(q.value.FieldByIndex(p.index))
+		}
+	}
+	return reflect.Value{}
+}
+
+// Ordered requires low to be at most high when both have a value. Both
+// parameters are reported. They are numbers or dates.
+func Ordered(low, high string) QueryRule {
+	return QueryRule{
+		params: []string{low, high},
+		check: func(q boundQuery) []error {
+			if !greater(q.field(low), q.field(high)) {
+				return nil
+			}
+			return []error{
+				validation.InField(low, validation.Errorf("not_greater_than", high)),
+				validation.InField(high, validation.Errorf("not_less_than", low)),
+			}
+		},
+	}
+}
+
+// Together requires the parameters to be set all or none. The missing ones
+// are reported.
+func Together(names ...string) QueryRule {
+	return QueryRule{
+		params: names,
+		check: func(q boundQuery) []error {
+			given := slices.DeleteFunc(slices.Clone(names), func(name string) bool { return !q.given[name] })
+			if len(given) == 0 || len(given) == len(names) {
+				return nil
+			}
+
+			var errs []error
+			for _, name := range names {
+				if !q.given[name] {
+					errs = append(errs, validation.InField(name, validation.Errorf("set_together", strings.Join(given, ", "))))
+				}
+			}
+			return errs
+		},
+	}
+}
+
+// Exclusive allows at most one of the parameters. Every given one is
+// reported.
+func Exclusive(names ...string) QueryRule {
+	return QueryRule{
+		params: names,
+		check: func(q boundQuery) []error {
+			given := slices.DeleteFunc(slices.Clone(names), func(name string) bool { return !q.given[name] })
+			if len(given) < 2 {
+				return nil
+			}
+
+			var errs []error
+			for _, name := range given {
+				others := slices.DeleteFunc(slices.Clone(given), func(other string) bool { return other == name })
+				errs = append(errs, validation.InField(name, validation.Errorf("exclusive", strings.Join(others, ", "))))
+			}
+			return errs
+		},
+	}
+}
+
+// Requires requires the parameters when name is set. The missing ones are
+// reported.
+func Requires(name string, required ...string) QueryRule {
+	return QueryRule{
+		params: append([]string{name}, required...),
+		check: func(q boundQuery) []error {
+			if !q.given[name] {
+				return nil
+			}
+			return missing(q, required, name)
+		},
+	}
+}
+
+// RequiresValue requires the parameters when name has the value, or holds it
+// for lists. Defaults count, so a default value needs its parameters too.
+func RequiresValue(name, value string, required ...string) QueryRule {
+	return QueryRule{
+		params: append([]string{name}, required...),
+		check: func(q boundQuery) []error {
+			if !slices.Contains(q.raws[name], value) {
+				return nil
+			}
+			return missing(q, required, name+"="+value)
+		},
+	}
+}
+
+// AtMost caps a number. Unlike the maximum tag, the cap is known only when
+// the middleware is made, e.g. from the configuration.
+func AtMost(name string, max float64) QueryRule {
+	return QueryRule{
+		params: []string{name},
+		check: func(q boundQuery) []error {
+			n, ok := number(q.field(name))
+			if !ok || n <= max {
+				return nil
+			}
+			return []error{validation.InField(name, validation.Errorf("at_most", max))}
+		},
+	}
+}
+
+func missing(q boundQuery, required []string, by string) []error {
+	var errs []error
+	for _, name := range required {
+		if !q.given[name] {
+			errs = append(errs, validation.InField(name, validation.Errorf("required_by", by)))
+		}
+	}
+	return errs
+}
+
+// greater reports whether a is greater than b. Missing values are never
+// greater.
+func greater(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return false
+	}
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).After(b.Interface().(time.Time))
+	}
+
+	x, okA := number(a)
+	y, okB := number(b)
+	return okA && okB && x > y
+}
+
+func number(v reflect.Value) (float64, bool) {
+	if !v.IsValid() {
+		return 0, false
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// mustRules checks that the rules name the parameters of the schema.
+func (s querySchema) mustRules(t reflect.Type, rules []QueryRule) {
+	for _, rule := range rules {
+		for _, name := range rule.params {
+			if !slices.ContainsFunc(s, func(p queryParam) bool { return p.name == name }) {
+				panic(fmt.Sprintf("query rule names unknown parameter %s of %s", name, t))
+			}
+		}
+	}
+}
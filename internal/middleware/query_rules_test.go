@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"marketplace-service/internal/validation"
+)
+
+type rulesQuery struct {
+	Low    *int      `form:"low"`
+	High   *int      `form:"high"`
+	From   time.Time `form:"from" format:"date"`
+	To     time.Time `form:"to" format:"date"`
+	Lat    *float64  `form:"lat"`
+	Lon    *float64  `form:"lon"`
+	Radius *float64  `form:"radius"`
+	City   string    `form:"city"`
+	Sort   []string  `form:"sort" defaultItems:"date"`
+	Limit  int       `form:"limit" default:"10"`
+}
+
+// reported decodes the query and lists the refused parameters as
+// "field:key".
+func reported(t *testing.T, rule QueryRule, query string) []string {
+	t.Helper()
+
+	schema := mustSchema(reflect.TypeFor[rulesQuery]())
+	schema.mustRules(reflect.TypeFor[rulesQuery](), []QueryRule{rule})
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q rulesQuery
+	err = schema.decode(values, reflect.ValueOf(&q).Elem(), []QueryRule{rule})
+
+	errs := []error{err}
+	var joined validation.Errors
+	if errors.As(err, &joined) {
+		errs = joined
+	}
+
+	var got []string
+	for _, err := range errs {
+		var e *validation.Error
+		if errors.As(err, &e) {
+			got = append(got, e.Field+":"+e.Key)
+		}
+	}
+	return got
+}
+
+func TestQueryRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  QueryRule
+		query string
+		want  []string
+	}{
+		{"ordered numbers", Ordered("low", "high"), "low=1&high=2", nil},
+		{"ordered equal", Ordered("low", "high"), "low=2&high=2", nil},
+		{"ordered one side", Ordered("low", "high"), "low=5", nil},
+		{"ordered reversed", Ordered("low", "high"), "low=5&high=2", []string{"low:not_greater_than", "high:not_less_than"}},
+		{"ordered dates", Ordered("from", "to"), "from=2024-01-01&to=2024-02-01", nil},
+		{"ordered dates reversed", Ordered("from", "to"), "from=2024-02-01&to=2024-01-01", []string{"from:not_greater_than", "to:not_less_than"}},
+
+		{"together none", Together("lat", "lon"), "", nil},
+		{"together all", Together("lat", "lon"), "lat=1&lon=2", nil},
+		{"together missing", Together("lat", "lon"), "lat=1", []string{"lon:set_together"}},
+		{"together empty counts as missing", Together("lat", "lon"), "lat=1&lon=", []string{"lon:set_together"}},
+
+		{"exclusive none", Exclusive("city", "radius"), "", nil},
+		{"exclusive one", Exclusive("city", "radius"), "city=Moscow", nil},
+		{"exclusive both", Exclusive("city", "radius"), "city=Moscow&radius=5", []string{"city:exclusive", "radius:exclusive"}},
+
+		{"requires unset", Requires("radius", "lat", "lon"), "lat=1", nil},
+		{"requires met", Requires("radius", "lat", "lon"), "radius=5&lat=1&lon=2", nil},
+		{"requires missing", Requires("radius", "lat", "lon"), "radius=5&lon=2", []string{"lat:required_by"}},
+		{"requires all missing", Requires("radius", "lat", "lon"), "radius=5", []string{"lat:required_by", "lon:required_by"}},
+
+		{"requires value other", RequiresValue("sort", "distance", "lat", "lon"), "sort=price", nil},
+		{"requires value default", RequiresValue("sort", "date", "lat"), "", []string{"lat:required_by"}},
+		{"requires value in list", RequiresValue("sort", "distance", "lat", "lon"), "sort=price,distance&lat=1", []string{"lon:required_by"}},
+		{"requires value met", RequiresValue("sort", "distance", "lat", "lon"), "sort=distance&lat=1&lon=2", nil},
+
+		{"at most default", AtMost("limit", 50), "", nil},
+		{"at most equal", AtMost("limit", 50), "limit=50", nil},
+		{"at most over", AtMost("limit", 50), "limit=51", []string{"limit:at_most"}},
+		{"at most nil pointer", AtMost("radius", 50), "", nil},
+		{"at most pointer over", AtMost("radius", 50), "radius=50.5", []string{"radius:at_most"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reported(t, tt.rule, tt.query)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("query %q reported %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryRulesRunAfterInvalidParameters(t *testing.T) {
+	got := reported(t, Ordered("low", "high"), "low=x&high=2")
+	if want := []string{"low:integer"}; !slices.Equal(got, want) {
+		t.Errorf("reported %v, want %v", got, want)
+	}
+}
+
+func TestQueryRuleUnknownParameter(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("rule with an unknown parameter did not panic")
+		}
+	}()
+	mustSchema(reflect.TypeFor[rulesQuery]()).mustRules(reflect.TypeFor[rulesQuery](), []QueryRule{Exclusive("city", "country")})
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"fmt"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRateLimitRoute is the route whose policy applies to the routes
+// without their own one.
+const DefaultRateLimitRoute = "*"
+
+// RateLimitPolicy allows Limit requests per Period, which may come in a burst.
+type RateLimitPolicy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRateLimitPolicies parses policies like
+// "POST /api/v1/announcements=10/1m;GET /api/v1/announcements=120/1m;*=300/1m".
+// The routes are the patterns the handlers are registered with.
+func ParseRateLimitPolicies(value string) (map[string]RateLimitPolicy, error) {
+	policies := make(map[string]RateLimitPolicy)
+
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		route, rate, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q must look like route=limit/period", item)
+		}
+		limit, period, ok := strings.Cut(rate, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q must look like route=limit/period", item)
+		}
+
+		var policy RateLimitPolicy
+		var err error
+		if policy.Limit, err = strconv.Atoi(strings.TrimSpace(limit)); err != nil || policy.Limit <= 0 {
+			return nil, fmt.Errorf("rate limit %q: limit must be a positive integer", item)
+		}
+		if policy.Period, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || policy.Period <= 0 {
+			return nil, fmt.Errorf("rate limit %q: period must be a positive duration", item)
+		}
+
+		policies[strings.TrimSpace(route)] = policy
+	}
+
+	return policies, nil
+}
+
+// RateLimiter limits requests with token buckets. Authorized users have their
+// own buckets, anonymous requests share the bucket of the client address.
+type RateLimiter struct {
+	store    store.RateLimitStore
+	token    *token.Service
+	logger   logger.Logger
+	policies map[string]RateLimitPolicy
+}
+
+func NewRateLimiter(store store.RateLimitStore, token *token.Service, logger logger.Logger, policies map[string]RateLimitPolicy) *RateLimiter {
+	return &RateLimiter{
+		store:    store,
+		token:    token,
+		logger:   logger,
+		policies: policies,
+	}
+}
+
+// Middleware limits the handler with the policy. The name separates the
+// buckets of different handlers.
+func (l *RateLimiter) Middleware(name string, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.allow(w, r, name, policy) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// Routes limits every route of the mux with the policy configured for its
+// pattern, or with the default policy.
+func (l *RateLimiter) Routes(mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, pattern := mux.Handler(r)
+
+			name := pattern
+			policy, ok := l.policies[pattern]
+			if !ok {
+				name = DefaultRateLimitRoute
+				policy, ok = l.policies[DefaultRateLimitRoute]
+			}
+
+			if !ok || pattern == "" || l.allow(w, r, name, policy) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func (l *RateLimiter) subject(r *http.Request) string {
+	if userId, err := l.token.ValidateToken(token.ExtractToken(r)); err == nil {
+		return "user:" + userId
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// allow takes a token for the request and sets the RateLimit headers. When
+// there is no token left it writes 429 itself. The requests are let through
+// if the store fails.
+func (l *RateLimiter) allow(w http.ResponseWriter, r *http.Request, name string, policy RateLimitPolicy) bool {
+	allowed, tokens, err := l.store.Take(name+"|"+l.subject(r), policy.Limit, policy.Period)
+	if err != nil {
+		l.logger.Info(err)
+		return true
+	}
+
+	perToken := policy.Period.Seconds() / float64(policy.Limit)
+	reset := math.Ceil((float64(policy.Limit) - tokens) * perToken)
+
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(math.Ceil(policy.Period.Seconds()))))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(tokens))))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(reset)))
+
+	if !allowed {
+		retryAfter := math.Ceil((1 - tokens) * perToken)
+		w.Header().Set("Retry-After", strconv.Itoa(int(max(retryAfter, 1))))
+		problem.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
@@ -1,15 +1,40 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AnnouncementActive   = "active"
+	AnnouncementReserved = "reserved"
+	AnnouncementArchived = "archived"
+	AnnouncementDraft    = "draft"
+)
 
 type Announcement struct {
-	Id            int64     `json:"id"`
-	UserId        int64     `json:"-"`
-	OwnerUsername string    `json:"owner_username"`
-	Article       string    `json:"title"`
-	Text          string    `json:"text"`
-	CostRubles    int32     `json:"price"`
-	ImageAddress  string    `json:"image_url"`
-	IsOwner       *bool     `json:"is_owner,omitempty"`
-	Date          time.Time `json:"created_at"`
+	Id             int64           `json:"id"`
+	UserId         int64           `json:"-"`
+	OwnerUsername  string          `json:"owner_username"`
+	OwnerRating    *float64        `json:"owner_rating"`
+	Article        string          `json:"title"`
+	Text           string          `json:"text"`
+	CostRubles     int32           `json:"price"`
+	PreviousPrice  *int32          `json:"previous_price"`
+	PriceChangedAt *time.Time      `json:"price_changed_at"`
+	ImageAddress   string          `json:"image_url"`
+	City           string          `json:"city"`
+	Latitude       *float64        `json:"latitude"`
+	Longitude      *float64        `json:"longitude"`
+	DistanceKm     *float64        `json:"distance_km,omitempty"`
+	CategoryId     *int64          `json:"-"`
+	Category       string          `json:"category"`
+	Attributes     json.RawMessage `json:"attributes" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Version        int64           `json:"version"`
+	ExpiresAt      *time.Time      `json:"expires_at"`
+	PublishAt      *time.Time      `json:"publish_at"`
+	IsOwner        *bool           `json:"is_owner,omitempty"`
+	Date           time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"-"`
 }
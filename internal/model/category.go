@@ -0,0 +1,37 @@
+package model
+
+const (
+	AttributeInt   = "int"
+	AttributeEnum  = "enum"
+	AttributeBool  = "bool"
+	AttributeRange = "range"
+)
+
+// AttributeSpec describes one attribute of the announcements in a category.
+// Min and Max bound int attributes and both ends of range attributes, Values
+// lists the allowed values of enum attributes.
+type AttributeSpec struct {
+	Name     string   `json:"name" example:"mileage"`
+	Type     string   `json:"type" example:"int" enums:"int,enum,bool,range"`
+	Required bool     `json:"required" example:"true"`
+	Min      *int64   `json:"min,omitempty" example:"0"`
+	Max      *int64   `json:"max,omitempty" example:"2000000"`
+	Values   []string `json:"values,omitempty"`
+}
+
+type Category struct {
+	Id         int64           `json:"id"`
+	Slug       string          `json:"slug" example:"cars"`
+	Name       string          `json:"name" example:"Автомобили"`
+	Attributes []AttributeSpec `json:"attributes" extensions:"x-nullable"`
+}
+
+// Attribute returns the spec of the attribute with the given name.
+func (c *Category) Attribute(name string) (AttributeSpec, bool) {
+	for _, spec := range c.Attributes {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return AttributeSpec{}, false
+}
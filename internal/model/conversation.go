@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+type Conversation struct {
+	Id                int64     `json:"id"`
+	AnnouncementId    int64     `json:"announcement_id"`
+	AnnouncementTitle string    `json:"announcement_title"`
+	BuyerId           int64     `json:"buyer_id"`
+	BuyerUsername     string    `json:"buyer_username"`
+	SellerId          int64     `json:"seller_id"`
+	SellerUsername    string    `json:"seller_username"`
+	UnreadCount       int       `json:"unread_count"`
+	Date              time.Time `json:"created_at"`
+	LastMessageAt     time.Time `json:"last_message_at"`
+}
+
+// Participant reports whether the user takes part in the conversation.
+func (c *Conversation) Participant(userId int64) bool {
+	return c.BuyerId == userId || c.SellerId == userId
+}
+
+// Interlocutor returns the id of the other participant of the conversation.
+func (c *Conversation) Interlocutor(userId int64) int64 {
+	if c.BuyerId == userId {
+		return c.SellerId
+	}
+	return c.BuyerId
+}
+
+type Message struct {
+	Id             int64     `json:"id"`
+	ConversationId int64     `json:"conversation_id"`
+	SenderId       int64     `json:"sender_id"`
+	Text           string    `json:"text"`
+	IsRead         bool      `json:"is_read"`
+	Date           time.Time `json:"created_at"`
+}
+
+// ListingInbox groups the conversations a seller has about one announcement.
+type ListingInbox struct {
+	AnnouncementId    int64          `json:"announcement_id"`
+	AnnouncementTitle string         `json:"announcement_title"`
+	UnreadCount       int            `json:"unread_count"`
+	Conversations     []Conversation `json:"conversations" extensions:"x-nullable"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "net/http"
+
+// IdempotentRequest is a request made with an Idempotency-Key. Until the
+// request is completed only the fingerprint of its body is known.
+type IdempotentRequest struct {
+	Fingerprint string
+	Completed   bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Notification struct {
+	Id      int64           `json:"id"`
+	UserId  int64           `json:"-"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload" swaggertype:"object" extensions:"x-nullable"`
+	IsRead  bool            `json:"is_read"`
+	Date    time.Time       `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+const (
+	OfferPending   = "pending"
+	OfferCountered = "countered"
+	OfferAccepted  = "accepted"
+	OfferDeclined  = "declined"
+	OfferExpired   = "expired"
+)
+
+type Offer struct {
+	Id             int64     `json:"id"`
+	AnnouncementId int64     `json:"announcement_id"`
+	BuyerId        int64     `json:"-"`
+	BuyerUsername  string    `json:"buyer_username"`
+	Amount         int32     `json:"amount"`
+	CounterAmount  *int32    `json:"counter_amount,omitempty"`
+	Status         string    `json:"status"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Date           time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
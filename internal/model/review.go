@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type Review struct {
+	Id             int64      `json:"id"`
+	SellerId       int64      `json:"-"`
+	SellerUsername string     `json:"seller_username"`
+	BuyerId        int64      `json:"-"`
+	BuyerUsername  string     `json:"buyer_username"`
+	Rating         int        `json:"rating"`
+	Text           string     `json:"text"`
+	Reply          *string    `json:"reply,omitempty"`
+	RepliedAt      *time.Time `json:"replied_at,omitempty"`
+	Date           time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
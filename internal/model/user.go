@@ -1,7 +1,24 @@
 package model
 
+import "time"
+
 type User struct {
-	ID       int64
-	Username string
-	Password string
+	ID          int64
+	Username    string
+	Password    string
+	DisplayName string
+	AvatarURL   string
+	Date        time.Time
+	IsAdmin     bool
+	BannedAt    *time.Time
+}
+
+type UserProfile struct {
+	Username            string    `json:"username" example:"CoolUsername"`
+	DisplayName         string    `json:"display_name" example:"Иван"`
+	AvatarURL           string    `json:"avatar_url" example:"http://example.com/images/avatar.jpg"`
+	Date                time.Time `json:"registered_at" example:"2025-07-16T22:39:54.789179Z"`
+	ActiveAnnouncements int       `json:"active_announcements" example:"4"`
+	Rating              *float64  `json:"rating" example:"4.5" extensions:"x-nullable"`
+	ReviewsCount        int       `json:"reviews_count" example:"12"`
 }
@@ -0,0 +1,59 @@
+package notifications
+
+import (
+	"sync"
+
+	"marketplace-service/internal/model"
+)
+
+// subscriberBuffer is how many notifications a slow stream may lag behind
+// before new ones are dropped for it. Dropped notifications are picked up
+// again by the stream's periodic catch-up query.
+const subscriberBuffer = 16
+
+// Broker fans out freshly created notifications to the streams opened by
+// their recipient within this process.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[int64]map[chan model.Notification]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[int64]map[chan model.Notification]struct{}),
+	}
+}
+
+func (b *Broker) Subscribe(userId int64) (<-chan model.Notification, func()) {
+	ch := make(chan model.Notification, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userId] == nil {
+		b.subscribers[userId] = make(map[chan model.Notification]struct{})
+	}
+	b.subscribers[userId][ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		delete(b.subscribers[userId], ch)
+		if len(b.subscribers[userId]) == 0 {
+			delete(b.subscribers, userId)
+		}
+		b.mu.Unlock()
+	}
+
+	return ch, unsubscribe
+}
+
+func (b *Broker) Publish(n model.Notification) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[n.UserId] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
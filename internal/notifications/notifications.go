@@ -0,0 +1,276 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type handler struct {
+	db        store.NotificationsStore
+	broker    *Broker
+	logger    logger.Logger
+	token     *token.Service
+	heartbeat time.Duration
+	maxLimit  int
+}
+
+func NewHandler(db store.NotificationsStore, broker *Broker, logger logger.Logger, token *token.Service, heartbeat time.Duration, maxLimit int) *handler {
+	return &handler{
+		db:        db,
+		broker:    broker,
+		logger:    logger,
+		token:     token,
+		heartbeat: heartbeat,
+		maxLimit:  maxLimit,
+	}
+}
+
+func (h *handler) RegisterService(mux *http.ServeMux) {
+	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
+
+	getNotificationsHandler := middleware.Chain(
+		middleware.AuthMiddleware(h.token, h.getNotifications),
+		middleware.BindQuery[NotificationsQuery](middleware.AtMost("limit", float64(h.maxLimit))),
+		loggerMiddleware,
+	)
+
+	mux.Handle("GET /api/v1/notifications", getNotificationsHandler)
+	mux.Handle("POST /api/v1/notifications/{id}/read", loggerMiddleware(middleware.AuthMiddleware(h.token, h.markRead)))
+	mux.Handle("POST /api/v1/notifications/read", loggerMiddleware(middleware.AuthMiddleware(h.token, h.markAllRead)))
+
+	// The stream is deliberately not wrapped into the logging middleware:
+	// it never finishes and the middleware would buffer the whole response.
+	mux.HandleFunc("GET /api/v1/notifications/stream", h.streamNotifications)
+}
+
+// NotificationsQuery is a page of the notifications of the current user.
+type NotificationsQuery struct {
+	middleware.PageQuery
+	// Filter by read status
+	Status string `form:"status" default:"all" enums:"all,read,unread"`
+}
+
+// GetNotifications gets a paginated list of notifications of the current user
+// @Summary      Get notifications list
+// @Description  Get a paginated list of notifications of the authorized user, newest first.
+// @Tags         Notifications
+// @Produce      json
+// @Param        query    query      NotificationsQuery  false  "Page and read status"
+// @Success      200      {array}    model.Notification
+// @Failure      400 {object}  problem.Problem  "Invalid query parameter"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/notifications [get]
+// @Security     Bearer
+func (h *handler) getNotifications(w http.ResponseWriter, r *http.Request) {
+	query := middleware.Query[NotificationsQuery](r)
+
+	userId, _ := middleware.GetUserId(r)
+
+	notifications, err := h.db.GetNotificationsByPage(userId, query.Page, query.Limit, query.Status)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, notifications)
+}
+
+// Mark notification as read
+// @Summary      Mark a notification as read
+// @Description  Mark a single notification of the authorized user as read.
+// @Tags         Notifications
+// @Param        id   path      int  true  "Notification id"
+// @Success      204  "Notification marked as read"
+// @Failure      400 {object}  problem.Problem  "Invalid notification id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      404 {object}  problem.Problem  "Notification not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/notifications/{id}/read [post]
+// @Security     Bearer
+func (h *handler) markRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid notification id", http.StatusBadRequest)
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+
+	if err := h.db.MarkNotificationRead(userId, id); err != nil {
+		if errors.Is(err, store.ErrNotificationNotFound) {
+			problem.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Mark all notifications as read
+// @Summary      Mark all notifications as read
+// @Description  Mark every unread notification of the authorized user as read.
+// @Tags         Notifications
+// @Success      204  "Notifications marked as read"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/notifications/read [post]
+// @Security     Bearer
+func (h *handler) markAllRead(w http.ResponseWriter, r *http.Request) {
+	userId, _ := middleware.GetUserId(r)
+
+	if err := h.db.MarkAllNotificationsRead(userId); err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Notifications stream
+// @Summary      Stream notifications
+// @Description  Server-Sent Events stream of new notifications of the authorized user.
+// @Description  Browsers' EventSource can not send headers, so the token may also be passed in the access_token query parameter.
+// @Description  Send Last-Event-ID to receive the notifications missed since that id. A comment line is sent as a heartbeat.
+// @Tags         Notifications
+// @Produce      text/event-stream
+// @Param        access_token   query   string  false  "JWT token, alternative to the Authorization header"
+// @Param        Last-Event-ID  header  int     false  "Id of the last received notification"
+// @Success      200  {string}  string  "Stream of notification events"
+// @Failure      400 {object}  problem.Problem  "Invalid Last-Event-ID"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/notifications/stream [get]
+// @Security     Bearer
+func (h *handler) streamNotifications(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.Authenticate(w, h.token, token.ExtractTokenOrQuery(r))
+	if !ok {
+		return
+	}
+
+	var lastId int64
+	if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+		var err error
+		lastId, err = strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil || lastId < 0 {
+			problem.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Subscribe before loading the backlog so nothing created in between is lost.
+	live, unsubscribe := h.broker.Subscribe(int64(userId))
+	defer unsubscribe()
+
+	// A fresh stream starts from the newest stored notification instead of
+	// replaying the whole history.
+	if r.Header.Get("Last-Event-ID") == "" {
+		latest, err := h.db.GetNotificationsByPage(userId, 1, 1, store.DefaultNotificationFilter)
+		if err != nil {
+			h.logger.Info(err)
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if len(latest) > 0 {
+			lastId = latest[0].Id
+		}
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(n model.Notification) error {
+		if n.Id <= lastId {
+			return nil
+		}
+
+		data, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", n.Id, data); err != nil {
+			return err
+		}
+		lastId = n.Id
+		return nil
+	}
+
+	// catchUp sends everything stored after lastId, a page of maxLimit at a
+	// time. It is also run on every heartbeat, which delivers notifications
+	// created by other instances of the service and the ones dropped for a
+	// slow subscriber.
+	catchUp := func() error {
+		for {
+			missed, err := h.db.GetNotificationsAfter(userId, lastId, h.maxLimit)
+			if err != nil {
+				return err
+			}
+			for _, n := range missed {
+				if err := send(n); err != nil {
+					return err
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+			if len(missed) < h.maxLimit {
+				return nil
+			}
+		}
+	}
+
+	if err := catchUp(); err != nil {
+		h.logger.Info(err)
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case n := <-live:
+			if err := send(n); err != nil {
+				h.logger.Info(err)
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := catchUp(); err != nil {
+				h.logger.Info(err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package notifications
+
+import (
+	"encoding/json"
+
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/store"
+)
+
+// Notifier is what other packages use to tell a user that something happened.
+type Notifier interface {
+	Notify(userId int64, kind string, payload any) error
+}
+
+type Service struct {
+	db     store.NotificationsStore
+	broker *Broker
+}
+
+func NewService(db store.NotificationsStore, broker *Broker) *Service {
+	return &Service{
+		db:     db,
+		broker: broker,
+	}
+}
+
+// Notify persists a notification for the user and pushes it to the user's open streams.
+func (s *Service) Notify(userId int64, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	n := model.Notification{UserId: userId, Type: kind, Payload: data}
+	if err := s.db.CreateNotification(&n); err != nil {
+		return err
+	}
+
+	s.broker.Publish(n)
+	return nil
+}
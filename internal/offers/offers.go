@@ -0,0 +1,432 @@
+package offers
+
+import (
+	"errors"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"net/http"
+	"time"
+)
+
+type handler struct {
+	db            store.OffersStore
+	announcements store.AnnouncementsStore
+	feed          store.FeedInvalidator
+	notifier      notifications.Notifier
+	logger        logger.Logger
+	token         *token.Service
+	ttl           time.Duration
+}
+
+type OfferPostRequest struct {
+	Amount int32 `json:"amount" example:"4500" validate:"required,min=1"`
+}
+
+func NewHandler(db store.OffersStore, announcements store.AnnouncementsStore, feed store.FeedInvalidator, notifier notifications.Notifier, logger logger.Logger, token *token.Service, ttl time.Duration) *handler {
+	return &handler{
+		db:            db,
+		announcements: announcements,
+		feed:          feed,
+		notifier:      notifier,
+		logger:        logger,
+		token:         token,
+		ttl:           ttl,
+	}
+}
+
+func (h *handler) RegisterService(mux *http.ServeMux) {
+	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
+
+	withAuth := func(next http.HandlerFunc) http.Handler {
+		return loggerMiddleware(middleware.AuthMiddleware(h.token, next))
+	}
+	withAuthAndOffer := func(next http.HandlerFunc) http.Handler {
+		return withAuth(middleware.BindBody[OfferPostRequest](next))
+	}
+
+	mux.Handle("POST /api/v1/announcements/{id}/offers", withAuthAndOffer(h.createOffer))
+	mux.Handle("GET /api/v1/announcements/{id}/offers", withAuth(h.getOffers))
+	mux.Handle("POST /api/v1/offers/{id}/accept", withAuth(h.acceptOffer))
+	mux.Handle("POST /api/v1/offers/{id}/decline", withAuth(h.declineOffer))
+	mux.Handle("POST /api/v1/offers/{id}/counter", withAuthAndOffer(h.counterOffer))
+}
+
+func (h *handler) notify(userId int64, kind string, o *model.Offer) {
+	err := h.notifier.Notify(userId, kind, map[string]any{
+		"offer_id":        o.Id,
+		"announcement_id": o.AnnouncementId,
+		"status":          o.Status,
+	})
+	if err != nil {
+		h.logger.Info(err)
+	}
+}
+
+// loadAnnouncement fetches the announcement by id. It writes the error response itself.
+func (h *handler) loadAnnouncement(w http.ResponseWriter, id int64) (*model.Announcement, bool) {
+	an, err := h.announcements.GetAnnouncementById(id, 0)
+	if err != nil {
+		if errors.Is(err, store.ErrAnnouncementNotFound) {
+			problem.Error(w, "Announcement not found", http.StatusNotFound)
+			return nil, false
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return an, true
+}
+
+// loadOffer fetches the offer from the path together with its announcement.
+// It writes the error response itself.
+func (h *handler) loadOffer(w http.ResponseWriter, r *http.Request) (*model.Offer, *model.Announcement, bool) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid offer id", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	offer, err := h.db.GetOfferById(id)
+	if err != nil {
+		if errors.Is(err, store.ErrOfferNotFound) {
+			problem.Error(w, "Offer not found", http.StatusNotFound)
+			return nil, nil, false
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	an, ok := h.loadAnnouncement(w, offer.AnnouncementId)
+	if !ok {
+		return nil, nil, false
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	if offer.BuyerId != int64(userId) && an.UserId != int64(userId) {
+		problem.Error(w, "Offer not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	return offer, an, true
+}
+
+// reload answers with the current state of the offer after a transition.
+func (h *handler) reload(w http.ResponseWriter, id int64) (*model.Offer, bool) {
+	offer, err := h.db.GetOfferById(id)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return offer, true
+}
+
+func (h *handler) transitionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrOfferNotOpen):
+		problem.Error(w, "The offer is no longer open", http.StatusConflict)
+	case errors.Is(err, store.ErrAnnouncementNotActive):
+		problem.Error(w, "The announcement is no longer active", http.StatusConflict)
+	default:
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// Offer creation
+// @Summary      Make an offer
+// @Description  Offer the owner of an active announcement a price not higher than the asked one. A buyer can have one open offer per announcement.
+// @Tags         Offers
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int               true  "Announcement id"
+// @Param        request  body  OfferPostRequest  true  "Offered price"
+// @Param        Idempotency-Key  header  string  false  "Repeating the request with the same key returns the first response"
+// @Success      201 {object} model.Offer "Offer successfully created"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload, amount above the price or own announcement"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      404 {object}  problem.Problem  "Announcement not found"
+// @Failure      409 {object}  problem.Problem  "Announcement is not active or there is an open offer already"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id}/offers [post]
+// @Security     Bearer
+func (h *handler) createOffer(w http.ResponseWriter, r *http.Request) {
+	announcementId, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	opr := middleware.Body[OfferPostRequest](r)
+
+	an, ok := h.loadAnnouncement(w, announcementId)
+	if !ok {
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	if an.UserId == int64(userId) {
+		problem.Error(w, "You can not make an offer on your own announcement", http.StatusBadRequest)
+		return
+	}
+
+	if an.Status != model.AnnouncementActive {
+		problem.Error(w, "The announcement is no longer active", http.StatusConflict)
+		return
+	}
+
+	if opr.Amount > an.CostRubles {
+		problem.Error(w, "The offer can not exceed the price of the announcement", http.StatusBadRequest)
+		return
+	}
+
+	offer := &model.Offer{
+		AnnouncementId: announcementId,
+		BuyerId:        int64(userId),
+		Amount:         opr.Amount,
+		ExpiresAt:      time.Now().Add(h.ttl),
+	}
+
+	if err := h.db.CreateOffer(offer); err != nil {
+		if errors.Is(err, store.ErrOfferAlreadyOpen) {
+			problem.Error(w, "You already have an open offer on this announcement", http.StatusConflict)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	offer, ok = h.reload(w, offer.Id)
+	if !ok {
+		return
+	}
+
+	h.notify(an.UserId, "offer.new", offer)
+	response.JSON(w, h.logger, http.StatusCreated, offer)
+}
+
+// GetOffers gets offers on an announcement
+// @Summary      Get offers on an announcement
+// @Description  The owner gets all offers on the announcement, other users get only their own ones.
+// @Tags         Offers
+// @Produce      json
+// @Param        id   path      int  true  "Announcement id"
+// @Success      200  {array}   model.Offer
+// @Failure      400 {object}  problem.Problem  "Invalid announcement id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      404 {object}  problem.Problem  "Announcement not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/announcements/{id}/offers [get]
+// @Security     Bearer
+func (h *handler) getOffers(w http.ResponseWriter, r *http.Request) {
+	announcementId, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	an, ok := h.loadAnnouncement(w, announcementId)
+	if !ok {
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+
+	buyerId := int64(userId)
+	if an.UserId == int64(userId) {
+		buyerId = 0
+	}
+
+	offers, err := h.db.GetOffersByAnnouncement(announcementId, buyerId)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, offers)
+}
+
+// Offer acceptance
+// @Summary      Accept an offer
+// @Description  The owner accepts a pending offer, or the buyer accepts the counter offer of the owner. The announcement becomes reserved and the other open offers on it are declined.
+// @Tags         Offers
+// @Produce      json
+// @Param        id   path      int  true  "Offer id"
+// @Success      200  {object}  model.Offer "Offer accepted"
+// @Failure      400 {object}  problem.Problem  "Invalid offer id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "It is not the user's turn to answer the offer"
+// @Failure      404 {object}  problem.Problem  "Offer not found"
+// @Failure      409 {object}  problem.Problem  "Offer is no longer open or announcement is not active"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/offers/{id}/accept [post]
+// @Security     Bearer
+func (h *handler) acceptOffer(w http.ResponseWriter, r *http.Request) {
+	offer, an, ok := h.loadOffer(w, r)
+	if !ok {
+		return
+	}
+
+	if offer.Status != model.OfferPending && offer.Status != model.OfferCountered {
+		problem.Error(w, "The offer is no longer open", http.StatusConflict)
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	if !answersOffer(offer, an, int64(userId)) {
+		problem.Error(w, "It is not your turn to answer the offer", http.StatusForbidden)
+		return
+	}
+
+	declined, err := h.db.AcceptOffer(offer.Id, offer.Status)
+	if err != nil {
+		h.transitionError(w, err)
+		return
+	}
+	// Accepting reserves the announcement.
+	h.feed.Invalidate()
+
+	for i := range declined {
+		h.notify(declined[i].BuyerId, "offer.declined", &declined[i])
+	}
+
+	offer, ok = h.reload(w, offer.Id)
+	if !ok {
+		return
+	}
+
+	h.notify(counterpart(offer, an, int64(userId)), "offer.accepted", offer)
+	response.JSON(w, h.logger, http.StatusOK, offer)
+}
+
+// Offer declining
+// @Summary      Decline an offer
+// @Description  The owner declines a pending offer, or the buyer declines the counter offer of the owner.
+// @Tags         Offers
+// @Produce      json
+// @Param        id   path      int  true  "Offer id"
+// @Success      200  {object}  model.Offer "Offer declined"
+// @Failure      400 {object}  problem.Problem  "Invalid offer id"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "It is not the user's turn to answer the offer"
+// @Failure      404 {object}  problem.Problem  "Offer not found"
+// @Failure      409 {object}  problem.Problem  "Offer is no longer open"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/offers/{id}/decline [post]
+// @Security     Bearer
+func (h *handler) declineOffer(w http.ResponseWriter, r *http.Request) {
+	offer, an, ok := h.loadOffer(w, r)
+	if !ok {
+		return
+	}
+
+	if offer.Status != model.OfferPending && offer.Status != model.OfferCountered {
+		problem.Error(w, "The offer is no longer open", http.StatusConflict)
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	if !answersOffer(offer, an, int64(userId)) {
+		problem.Error(w, "It is not your turn to answer the offer", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.DeclineOffer(offer.Id, offer.Status); err != nil {
+		h.transitionError(w, err)
+		return
+	}
+
+	offer, ok = h.reload(w, offer.Id)
+	if !ok {
+		return
+	}
+
+	h.notify(counterpart(offer, an, int64(userId)), "offer.declined", offer)
+	response.JSON(w, h.logger, http.StatusOK, offer)
+}
+
+// Counter offer
+// @Summary      Counter an offer
+// @Description  The owner answers a pending offer with another price, between the offered and the asked one. The buyer may then accept or decline it.
+// @Tags         Offers
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int               true  "Offer id"
+// @Param        request  body  OfferPostRequest  true  "Counter price"
+// @Success      200  {object}  model.Offer "Offer countered"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload or amount out of range"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "Only the owner can counter an offer"
+// @Failure      404 {object}  problem.Problem  "Offer not found"
+// @Failure      409 {object}  problem.Problem  "Offer is not pending"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/offers/{id}/counter [post]
+// @Security     Bearer
+func (h *handler) counterOffer(w http.ResponseWriter, r *http.Request) {
+	offer, an, ok := h.loadOffer(w, r)
+	if !ok {
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	if an.UserId != int64(userId) {
+		problem.Error(w, "Only the owner can counter an offer", http.StatusForbidden)
+		return
+	}
+
+	if offer.Status != model.OfferPending {
+		problem.Error(w, "Only a pending offer can be countered", http.StatusConflict)
+		return
+	}
+
+	opr := middleware.Body[OfferPostRequest](r)
+
+	if opr.Amount <= offer.Amount || opr.Amount > an.CostRubles {
+		problem.Error(w, "The counter offer must be above the offer and not above the price", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.CounterOffer(offer.Id, opr.Amount, time.Now().Add(h.ttl)); err != nil {
+		h.transitionError(w, err)
+		return
+	}
+
+	offer, ok = h.reload(w, offer.Id)
+	if !ok {
+		return
+	}
+
+	h.notify(offer.BuyerId, "offer.countered", offer)
+	response.JSON(w, h.logger, http.StatusOK, offer)
+}
+
+// answersOffer reports whether it is the user's turn: the owner answers
+// pending offers, the buyer answers counter offers.
+func answersOffer(o *model.Offer, an *model.Announcement, userId int64) bool {
+	switch o.Status {
+	case model.OfferPending:
+		return an.UserId == userId
+	case model.OfferCountered:
+		return o.BuyerId == userId
+	}
+	return false
+}
+
+// counterpart returns the other side of the bargaining.
+func counterpart(o *model.Offer, an *model.Announcement, userId int64) int64 {
+	if o.BuyerId == userId {
+		return an.UserId
+	}
+	return o.BuyerId
+}
@@ -0,0 +1,428 @@
+package openapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"marketplace-service/docs"
+	"marketplace-service/internal/announcements"
+	"marketplace-service/internal/auth"
+	"marketplace-service/internal/categories"
+	"marketplace-service/internal/conversations"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/openapi"
+	"marketplace-service/internal/register"
+	"marketplace-service/internal/reviews"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"marketplace-service/internal/users"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The stores below answer with fixed data. They embed the interfaces, so a
+// method the cases do not reach panics instead of answering wrongly.
+
+const (
+	ownerId  = 1
+	buyerId  = 2
+	bannedId = 4
+)
+
+var (
+	created = time.Date(2025, 7, 16, 22, 39, 54, 0, time.UTC)
+	rating  = 4.5
+)
+
+type userStore struct{ store.UserStore }
+
+func (userStore) CreateUser(user *model.User) (int64, error) {
+	if user.Username == "seller" {
+		return 0, store.ErrUserAlreadyExists
+	}
+	return 3, nil
+}
+
+func (userStore) GetUserByCredentials(username, password string) (*model.User, error) {
+	switch {
+	case username == "banned":
+		return nil, store.ErrUserBanned
+	case username != "seller" || password != "StrongP@ssw0rd!":
+		return nil, store.ErrInvalidUsernameOrPassword
+	}
+	return &model.User{ID: ownerId, Username: username}, nil
+}
+
+func (userStore) IsBanned(id int64) (bool, error) {
+	return id == bannedId, nil
+}
+
+func (userStore) GetUserIdByUsername(username string) (int64, error) {
+	if username != "seller" {
+		return 0, store.ErrUserNotFound
+	}
+	return ownerId, nil
+}
+
+func (s userStore) GetProfileByUsername(username string) (*model.UserProfile, error) {
+	if username != "seller" {
+		return nil, store.ErrUserNotFound
+	}
+	return s.GetProfileById(ownerId)
+}
+
+func (userStore) GetProfileById(id int64) (*model.UserProfile, error) {
+	profile := &model.UserProfile{Username: "seller", DisplayName: "Иван", Date: created, ActiveAnnouncements: 1}
+	if id == ownerId {
+		profile.Rating, profile.ReviewsCount = &rating, 2
+	}
+	return profile, nil
+}
+
+func (userStore) UpdateProfile(id int64, displayName, avatarURL *string) error {
+	return nil
+}
+
+type categoriesStore struct{ store.CategoriesStore }
+
+var furniture = model.Category{
+	Id:   1,
+	Slug: "furniture",
+	Name: "Мебель",
+	Attributes: []model.AttributeSpec{
+		{Name: "condition", Type: model.AttributeEnum, Values: []string{"new", "used"}},
+	},
+}
+
+func (categoriesStore) GetCategories() ([]model.Category, error) {
+	return []model.Category{furniture, {Id: 2, Slug: "other", Name: "Другое"}}, nil
+}
+
+func (categoriesStore) GetCategoryBySlug(slug string) (*model.Category, error) {
+	if slug != furniture.Slug {
+		return nil, store.ErrCategoryNotFound
+	}
+	category := furniture
+	return &category, nil
+}
+
+type announcementsStore struct {
+	store.AnnouncementsStore
+	store.PriceAlertsStore
+}
+
+// announcement is published by the owner, has a location, a category and a
+// price drop, so that every nullable field is set. The second one of the feed
+// has none of them.
+func announcement() model.Announcement {
+	previous, expires, categoryId := int32(6000), created.Add(720*time.Hour), furniture.Id
+	lat, lon := 55.7558, 37.6173
+	return model.Announcement{
+		Id:             10,
+		UserId:         ownerId,
+		OwnerUsername:  "seller",
+		OwnerRating:    &rating,
+		Article:        "Продам старый диван",
+		Text:           "Продается диван б/у, в хорошем состоянии",
+		CostRubles:     5000,
+		PreviousPrice:  &previous,
+		PriceChangedAt: &created,
+		City:           "Москва",
+		Latitude:       &lat,
+		Longitude:      &lon,
+		CategoryId:     &categoryId,
+		Category:       furniture.Slug,
+		Attributes:     json.RawMessage(`{"condition":"used"}`),
+		Status:         model.AnnouncementActive,
+		Version:        3,
+		ExpiresAt:      &expires,
+		Date:           created,
+		UpdatedAt:      created,
+	}
+}
+
+func (announcementsStore) GetAnnouncementsByPage(q store.AnnouncementQuery) ([]model.Announcement, error) {
+	bare := model.Announcement{Id: 11, UserId: buyerId, OwnerUsername: "buyer", Article: "Отдам кота", Text: "Кот в добрые руки", Status: model.AnnouncementActive, Version: 1, Date: created, UpdatedAt: created}
+	return []model.Announcement{announcement(), bare}, nil
+}
+
+func (announcementsStore) GetAnnouncementsByOwner(username string, page, limit, currentUserId int) ([]model.Announcement, error) {
+	return []model.Announcement{announcement()}, nil
+}
+
+func (announcementsStore) GetAnnouncementById(id int64, currentUserId int) (*model.Announcement, error) {
+	if id != 10 {
+		return nil, store.ErrAnnouncementNotFound
+	}
+	an := announcement()
+	return &an, nil
+}
+
+func (announcementsStore) CreateAnnouncement(an *model.Announcement) error {
+	an.Id, an.Version, an.Date = 12, 1, created
+	return nil
+}
+
+func (announcementsStore) UpdateAnnouncement(an *model.Announcement) (int32, error) {
+	return an.CostRubles, nil
+}
+
+type notificationsStore struct{ store.NotificationsStore }
+
+func (notificationsStore) GetNotificationsByPage(userId, page, limit int, filter string) ([]model.Notification, error) {
+	return []model.Notification{
+		{Id: 2, Type: "message.new", Payload: json.RawMessage(`{"conversation_id":1}`), Date: created},
+		{Id: 1, Type: "announcement.expiring", IsRead: true, Date: created},
+	}, nil
+}
+
+func (notificationsStore) GetNotificationsAfter(userId int, lastId int64, limit int) ([]model.Notification, error) {
+	if lastId > 0 {
+		return nil, nil
+	}
+	return []model.Notification{{Id: 1, Type: "announcement.expiring", Date: created}}, nil
+}
+
+type reviewsStore struct{ store.ReviewsStore }
+
+func (reviewsStore) GetReviewsBySeller(sellerId int64, page, limit int) ([]model.Review, error) {
+	reply := "Спасибо!"
+	return []model.Review{
+		{Id: 1, SellerUsername: "seller", BuyerUsername: "buyer", Rating: 5, Text: "Все отлично", Reply: &reply, RepliedAt: &created, Date: created, UpdatedAt: created},
+		{Id: 2, SellerUsername: "seller", BuyerUsername: "other", Rating: 4, Date: created, UpdatedAt: created},
+	}, nil
+}
+
+type conversationsStore struct{ store.ConversationsStore }
+
+func conversation() model.Conversation {
+	return model.Conversation{Id: 1, AnnouncementId: 10, AnnouncementTitle: "Продам старый диван", BuyerId: buyerId, BuyerUsername: "buyer", SellerId: ownerId, SellerUsername: "seller", UnreadCount: 1, Date: created, LastMessageAt: created}
+}
+
+func (conversationsStore) GetConversationsByPage(userId int64, page, limit int) ([]model.Conversation, error) {
+	return []model.Conversation{conversation()}, nil
+}
+
+func (conversationsStore) GetSellerInbox(sellerId int64, page, limit int) ([]model.ListingInbox, error) {
+	return []model.ListingInbox{{AnnouncementId: 10, AnnouncementTitle: "Продам старый диван", UnreadCount: 1, Conversations: []model.Conversation{conversation()}}}, nil
+}
+
+func (conversationsStore) CountUnreadMessages(userId int64) (int, error) {
+	return 1, nil
+}
+
+type notifier struct{}
+
+func (notifier) Notify(userId int64, kind string, payload any) error {
+	return nil
+}
+
+// newServer wires the handlers like cmd/main.go does, behind the contract
+// middleware that checks the responses too.
+func newServer(t *testing.T) (http.Handler, *token.Service) {
+	t.Helper()
+
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+
+	doc, err := openapi.Load(docs.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract, err := middleware.NewContract(doc, l, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := token.NewService("secret", time.Hour, l)
+	tokens.RejectBanned(func(userID string) (bool, error) {
+		id, err := strconv.ParseInt(userID, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		return userStore{}.IsBanned(id)
+	})
+	lockout := auth.NewLockout(store.NewMemoryLoginAttemptsStore(),
+		auth.LockoutPolicy{MaxFailures: 100, Window: time.Minute, Lockout: time.Minute, MaxLockout: time.Hour},
+		auth.LockoutPolicy{MaxFailures: 100, Window: time.Minute, Lockout: time.Minute, MaxLockout: time.Hour},
+	)
+
+	var users_ userStore
+	var announcementStore announcementsStore
+	mux := http.NewServeMux()
+	register.NewHandler(users_, l, tokens).RegisterRoutes(mux)
+	auth.NewHandler(users_, lockout, l, tokens).RegisterService(mux)
+	users.NewHandler(users_, l, tokens).RegisterService(mux)
+	categories.NewHandler(categoriesStore{}, l).RegisterService(mux)
+	announcements.NewHandler(announcementStore, announcementStore, categoriesStore{}, notifier{}, l, tokens, 720*time.Hour, 30*time.Second, 100).RegisterService(mux)
+	notifications.NewHandler(notificationsStore{}, notifications.NewBroker(), l, tokens, time.Second, 100).RegisterService(mux)
+	reviews.NewHandler(reviewsStore{}, users_, store.NoFeedCache{}, notifier{}, l, tokens, time.Hour, 100).RegisterService(mux)
+	conversations.NewHandler(conversationsStore{}, nil, notifier{}, l, tokens, 100).RegisterService(mux)
+
+	return contract.Middleware(mux), tokens
+}
+
+func TestResponsesMatchTheDocument(t *testing.T) {
+	server, tokens := newServer(t)
+	bearer, err := tokens.GenerateToken("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	banned, err := tokens.GenerateToken(strconv.Itoa(bannedId))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		header map[string]string
+		auth   bool
+		status int
+	}{
+		{method: "POST", path: "/api/v1/users", body: `{"username":"buyer2","password":"StrongP@ssw0rd!"}`, status: 201},
+		{method: "POST", path: "/api/v1/users", body: `{"username":"seller","password":"StrongP@ssw0rd!"}`, status: 400},
+		{method: "POST", path: "/api/v1/users", body: `{"username":"me","password":"weak"}`, status: 400},
+		{method: "POST", path: "/api/v1/users", body: `{"username":"buyer2"}`, status: 400},
+		{method: "POST", path: "/api/v1/auth", body: `{"username":"seller","password":"StrongP@ssw0rd!"}`, status: 201},
+		{method: "POST", path: "/api/v1/auth", body: `{"username":"seller","password":"WrongP@ssw0rd!"}`, status: 400},
+		{method: "POST", path: "/api/v1/auth", body: `{"username":"banned","password":"StrongP@ssw0rd!"}`, status: 403},
+		{method: "GET", path: "/api/v1/users/seller", status: 200},
+		{method: "GET", path: "/api/v1/users/nobody", status: 404},
+		{method: "PATCH", path: "/api/v1/users/me", body: `{"display_name":"Иван"}`, auth: true, status: 200},
+		{method: "PATCH", path: "/api/v1/users/me", body: `{"display_name":"Иван"}`, status: 401},
+		{method: "GET", path: "/api/v1/categories", status: 200},
+		{method: "GET", path: "/api/v1/announcements", status: 200},
+		{method: "GET", path: "/api/v1/announcements?sort_by=price_asc,date_desc&min_price=100&max_price=10000", auth: true, status: 200},
+		{method: "GET", path: "/api/v1/announcements?lat=55.75&lon=37.61&radius_km=10&sort_by=distance", status: 200},
+		{method: "GET", path: "/api/v1/announcements?min_price=10&max_price=5", status: 400},
+		{method: "GET", path: "/api/v1/announcements?page=100001", status: 400},
+		{method: "GET", path: "/api/v1/announcements?category=furniture&attr.condition=used", status: 200},
+		{method: "GET", path: "/api/v1/announcements/10", status: 200},
+		{method: "GET", path: "/api/v1/announcements/404", status: 404},
+		{method: "GET", path: "/api/v1/users/seller/announcements", status: 200},
+		{method: "POST", path: "/api/v1/announcements", auth: true, status: 201,
+			body: `{"article":"Продам старый диван","text":"Продается диван б/у, в хорошем состоянии","cost":5000,"category":"furniture","attributes":{"condition":"used"}}`},
+		{method: "POST", path: "/api/v1/announcements", auth: true, status: 400, body: `{"article":"Диван","text":"Короткий","cost":-1}`},
+		{method: "POST", path: "/api/v1/announcements", auth: true, status: 400, body: `{"unknown":true}`},
+		{method: "POST", path: "/api/v1/announcements", status: 401, body: `{"article":"Продам старый диван","text":"Продается диван б/у, в хорошем состоянии","cost":5000}`},
+		{method: "PATCH", path: "/api/v1/announcements/10", auth: true, header: map[string]string{"If-Match": `"3"`}, body: `{"cost":4500}`, status: 200},
+		{method: "PATCH", path: "/api/v1/announcements/10", auth: true, header: map[string]string{"If-Match": `W/"3"`}, body: `{"cost":4500}`, status: 412},
+		{method: "PATCH", path: "/api/v1/announcements/10", auth: true, body: `{"cost":4500}`, status: 400},
+		{method: "GET", path: "/api/v1/notifications?status=unread", auth: true, status: 200},
+		{method: "GET", path: "/api/v1/notifications", status: 401},
+		{method: "GET", path: "/api/v1/notifications", header: map[string]string{"Authorization": "Bearer " + banned}, status: 401},
+		{method: "GET", path: "/api/v1/users/seller/reviews", status: 200},
+		{method: "GET", path: "/api/v1/users/nobody/reviews", status: 404},
+		{method: "GET", path: "/api/v1/conversations", auth: true, status: 200},
+		{method: "GET", path: "/api/v1/conversations/inbox", auth: true, status: 200},
+		{method: "GET", path: "/api/v1/conversations/unread", auth: true, status: 200},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			var body io.Reader
+			if c.body != "" {
+				body = strings.NewReader(c.body)
+			}
+			r := httptest.NewRequest(c.method, c.path, body)
+			if c.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			if c.auth {
+				r.Header.Set("Authorization", "Bearer "+bearer)
+			}
+			for key, value := range c.header {
+				r.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Errorf("got %d, want %d: %s", w.Code, c.status, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestStreamPassesTheResponseCheck opens the notification stream with the
+// responses checked. The stream must not be buffered for the check.
+func TestStreamPassesTheResponseCheck(t *testing.T) {
+	handler, tokens := newServer(t)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	bearer, err := tokens.GenerateToken("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/notifications/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+bearer)
+	r.Header.Set("Accept", "text/event-stream")
+	r.Header.Set("Last-Event-ID", "0")
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got %d %q, want 200 text/event-stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() == "id: 1" {
+			return
+		}
+	}
+	t.Fatalf("the stream ended before the first event: %v", scanner.Err())
+}
+
+// TestBanIsCheckedOncePerRequest counts the ban lookups of authenticated
+// requests: the handlers read the user from the context instead of checking
+// the token again.
+func TestBanIsCheckedOncePerRequest(t *testing.T) {
+	server, tokens := newServer(t)
+	bearer, err := tokens.GenerateToken("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lookups int
+	tokens.RejectBanned(func(userID string) (bool, error) {
+		lookups++
+		return false, nil
+	})
+
+	for _, path := range []string{"/api/v1/announcements", "/api/v1/announcements/10", "/api/v1/notifications"} {
+		lookups = 0
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Authorization", "Bearer "+bearer)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK || lookups != 1 {
+			t.Errorf("%s: got %d with %d ban lookups, want 200 with 1", path, w.Code, lookups)
+		}
+	}
+}
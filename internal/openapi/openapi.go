@@ -0,0 +1,160 @@
+// Package openapi turns the Swagger 2.0 description generated by swag into an
+// OpenAPI 3.1 document, which the contract middleware checks the traffic
+// against.
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Version is the version of the documents made by Convert.
+const Version = "3.1.0"
+
+const (
+	problemSchema      = "#/components/schemas/problem.Problem"
+	problemContentType = "application/problem+json"
+)
+
+// Convert makes an OpenAPI 3.1 document of the Swagger 2.0 one. Fields
+// marked x-nullable accept null, and the errors are described with the
+// content type they are sent with. Examples are not checked, they are
+// written for people.
+func Convert(swagger []byte) (*openapi3.T, error) {
+	var doc2 openapi2.T
+	if err := json.Unmarshal(swagger, &doc2); err != nil {
+		return nil, err
+	}
+
+	doc3, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, err
+	}
+
+	// The validator of the document knows OpenAPI 3.0 only, so the document
+	// is checked before the upgrade.
+	if err := doc3.Validate(context.Background(), openapi3.DisableExamplesValidation()); err != nil {
+		return nil, err
+	}
+
+	doc3.OpenAPI = Version
+	// The paths start with /api/v1 themselves, the base path of swag is only
+	// used by the UI.
+	doc3.Servers = openapi3.Servers{{URL: "/"}}
+
+	for _, schema := range doc3.Components.Schemas {
+		upgradeSchema(schema, map[*openapi3.Schema]bool{})
+	}
+	for path, item := range doc3.Paths.Map() {
+		for method, op := range item.Operations() {
+			upgradeOperation(op)
+			setCollectionFormats(op, doc2.Paths[path].Operations()[method])
+		}
+	}
+
+	return doc3, nil
+}
+
+// Marshal writes the document the way cmd/openapi stores it in
+// docs/openapi.json.
+func Marshal(doc *openapi3.T) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Load reads an OpenAPI document made by Convert. The document has been
+// checked when it was made, only the references are resolved.
+func Load(data []byte) (*openapi3.T, error) {
+	return openapi3.NewLoader().LoadFromData(data)
+}
+
+func upgradeOperation(op *openapi3.Operation) {
+	for _, param := range op.Parameters {
+		if param.Value != nil {
+			upgradeSchema(param.Value.Schema, map[*openapi3.Schema]bool{})
+		}
+	}
+	if op.RequestBody != nil && op.RequestBody.Value != nil {
+		for _, media := range op.RequestBody.Value.Content {
+			upgradeSchema(media.Schema, map[*openapi3.Schema]bool{})
+		}
+	}
+
+	for _, response := range op.Responses.Map() {
+		if response.Value == nil {
+			continue
+		}
+		content := response.Value.Content
+		for contentType, media := range content {
+			upgradeSchema(media.Schema, map[*openapi3.Schema]bool{})
+
+			if media.Schema != nil && media.Schema.Ref == problemSchema && contentType != problemContentType {
+				delete(content, contentType)
+				content[problemContentType] = media
+			}
+		}
+	}
+}
+
+// setCollectionFormats keeps the formats of the array parameters, which
+// openapi2conv drops.
+func setCollectionFormats(op *openapi3.Operation, op2 *openapi2.Operation) {
+	if op2 == nil {
+		return
+	}
+
+	for _, param2 := range op2.Parameters {
+		param := op.Parameters.GetByInAndName(param2.In, param2.Name)
+		if param == nil || param2.Type == nil || !param2.Type.Is(openapi3.TypeArray) {
+			continue
+		}
+
+		switch param2.CollectionFormat {
+		case "", "csv":
+			param.Style, param.Explode = openapi3.SerializationForm, openapi3.Ptr(false)
+		case "ssv":
+			param.Style, param.Explode = openapi3.SerializationSpaceDelimited, openapi3.Ptr(false)
+		case "pipes":
+			param.Style, param.Explode = openapi3.SerializationPipeDelimited, openapi3.Ptr(false)
+		case "multi":
+			param.Style, param.Explode = openapi3.SerializationForm, openapi3.Ptr(true)
+		}
+	}
+}
+
+// upgradeSchema replaces nullable of OpenAPI 3.0 with the null type of
+// OpenAPI 3.1. References are upgraded where they are defined.
+func upgradeSchema(ref *openapi3.SchemaRef, seen map[*openapi3.Schema]bool) {
+	if ref == nil || ref.Ref != "" || ref.Value == nil || seen[ref.Value] {
+		return
+	}
+	schema := ref.Value
+	seen[schema] = true
+
+	if schema.Nullable {
+		schema.Nullable = false
+		if schema.Type != nil && !schema.Type.Includes(openapi3.TypeNull) {
+			types := append(slices.Clone(*schema.Type), openapi3.TypeNull)
+			schema.Type = (*openapi3.Types)(&types)
+		}
+	}
+
+	for _, property := range schema.Properties {
+		upgradeSchema(property, seen)
+	}
+	upgradeSchema(schema.Items, seen)
+	upgradeSchema(schema.AdditionalProperties.Schema, seen)
+	for _, list := range []openapi3.SchemaRefs{schema.AllOf, schema.OneOf, schema.AnyOf} {
+		for _, s := range list {
+			upgradeSchema(s, seen)
+		}
+	}
+}
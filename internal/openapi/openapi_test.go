@@ -0,0 +1,33 @@
+package openapi
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestDocumentIsUpToDate fails when docs/openapi.json was not regenerated
+// after swag had changed docs/swagger.json. Run make docs to fix it.
+func TestDocumentIsUpToDate(t *testing.T) {
+	swagger, err := os.ReadFile("../../docs/swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := os.ReadFile("../../docs/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Convert(swagger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(generated, committed) {
+		t.Error("docs/openapi.json differs from the conversion of docs/swagger.json, run make docs")
+	}
+}
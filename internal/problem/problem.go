@@ -0,0 +1,99 @@
+// Package problem writes error responses as RFC 7807 problem details. The
+// validation problems with the errors of the fields are made by the
+// validation package.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of the problem details.
+const ContentType = "application/problem+json"
+
+// typePrefix starts the type URIs. The URIs are relative to the API and stay
+// the same while the messages change.
+const typePrefix = "/problems/"
+
+const (
+	TypeValidation           = typePrefix + "validation-error"
+	TypeBadRequest           = typePrefix + "bad-request"
+	TypeUnauthorized         = typePrefix + "unauthorized"
+	TypeForbidden            = typePrefix + "forbidden"
+	TypeNotFound             = typePrefix + "not-found"
+	TypeConflict             = typePrefix + "conflict"
+	TypePreconditionFailed   = typePrefix + "precondition-failed"
+	TypeTooLarge             = typePrefix + "payload-too-large"
+	TypeUnprocessable        = typePrefix + "unprocessable-entity"
+	TypePreconditionRequired = typePrefix + "precondition-required"
+	TypeTooManyRequests      = typePrefix + "too-many-requests"
+	TypeInternal             = typePrefix + "internal-error"
+)
+
+var statusTypes = map[int]string{
+	http.StatusBadRequest:            TypeBadRequest,
+	http.StatusUnauthorized:          TypeUnauthorized,
+	http.StatusForbidden:             TypeForbidden,
+	http.StatusNotFound:              TypeNotFound,
+	http.StatusConflict:              TypeConflict,
+	http.StatusPreconditionFailed:    TypePreconditionFailed,
+	http.StatusRequestEntityTooLarge: TypeTooLarge,
+	http.StatusUnprocessableEntity:   TypeUnprocessable,
+	http.StatusPreconditionRequired:  TypePreconditionRequired,
+	http.StatusTooManyRequests:       TypeTooManyRequests,
+	http.StatusInternalServerError:   TypeInternal,
+}
+
+// Problem is the body of an error response.
+type Problem struct {
+	Type   string       `json:"type" example:"/problems/validation-error"`
+	Title  string       `json:"title" example:"Validation failed"`
+	Status int          `json:"status" example:"400"`
+	Detail string       `json:"detail,omitempty" example:"The request has invalid fields"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a failed check of one field of the request.
+type FieldError struct {
+	Field   string `json:"field" example:"password"`
+	Rule    string `json:"rule" example:"min"`
+	Message string `json:"message" example:"must be at least 8 characters long"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// New makes a problem of the type that stands for the status.
+func New(status int, detail string) *Problem {
+	typ, ok := statusTypes[status]
+	if !ok {
+		typ = "about:blank"
+	}
+
+	return &Problem{
+		Type:   typ,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Write writes the problem as the response.
+func Write(w http.ResponseWriter, p *Problem) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", ContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error replies with a problem of the type that stands for the status. It
+// takes the place of http.Error.
+func Error(w http.ResponseWriter, detail string, status int) {
+	Write(w, New(status, detail))
+}
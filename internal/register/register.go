@@ -7,28 +7,20 @@ import (
 	"marketplace-service/internal/logger"
 	"marketplace-service/internal/middleware"
 	"marketplace-service/internal/model"
+	"marketplace-service/internal/problem"
 	"marketplace-service/internal/store"
 	"marketplace-service/internal/token"
 	"net/http"
-
-	"github.com/go-playground/validator/v10"
 )
 
-var validate *validator.Validate
-
-func init() {
-	validate = validator.New()
-}
-
 type RegisterRequest struct {
-	Username string `json:"username" example:"testUser123" validate:"required,min=1,max=32"`
-	Password string `json:"password" example:"StrongP@ssw0rd!" validate:"required,min=8,max=64"`
+	Username string `json:"username" example:"testUser123" validate:"required,min=1,max=32,username"`
+	Password string `json:"password" example:"StrongP@ssw0rd!" validate:"required,min=8,max=64,password"`
 }
 
 type RegisterResponse struct {
 	UserId   int    `json:"user_id" example:"10"`
 	Username string `json:"username" example:"CoolUsername"`
-	Password string `json:"password" example:"CoolPassword"`
 }
 
 type handler struct {
@@ -50,7 +42,7 @@ func (h *handler) RegisterRoutes(mux *http.ServeMux) {
 	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
 
 	finalHandler := middleware.Chain(
-		http.HandlerFunc(h.registerNewUser),
+		middleware.BindBody[RegisterRequest](h.registerNewUser),
 		authMiddleware,
 		loggerMiddleware,
 	)
@@ -66,43 +58,32 @@ func (h *handler) RegisterRoutes(mux *http.ServeMux) {
 // @Produce      json
 // @Param        request body RegisterRequest true "User registration details"
 // @Success      201 {object} RegisterResponse "User successfully registered"
-// @Failure      400 "Invalid request payload or user already exists"
-// @Failure      500 "Internal server error"
+// @Header       201 {string} Authorization "Bearer token of the new user"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload or user already exists"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
 // @Router       /api/v1/users [post]
 func (h *handler) registerNewUser(w http.ResponseWriter, r *http.Request) {
-	var requestData RegisterRequest
-
-	err := json.NewDecoder(r.Body).Decode(&requestData)
-	if err != nil {
-		h.logger.Error("Failed to decode request body: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
-
-	if err := validate.Struct(requestData); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	requestData := middleware.Body[RegisterRequest](r)
 
 	user := &model.User{Username: requestData.Username, Password: requestData.Password}
 	id, err := h.db.CreateUser(user)
 
 	if err != nil {
 		if errors.Is(err, store.ErrUserAlreadyExists) {
-			http.Error(w, "User with this username already exists", http.StatusBadRequest)
+			problem.Error(w, "User with this username already exists", http.StatusBadRequest)
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 	
-	response := RegisterResponse{UserId: int(id), Username: user.Username, Password: user.Password} 
+	response := RegisterResponse{UserId: int(id), Username: user.Username}
 
 	token, _ := h.token.GenerateToken(fmt.Sprintf("%d", id))
 
 	w.Header().Set("Authorization", "Bearer "+token)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,19 @@
+// Package response writes the successful JSON responses of the handlers. The
+// errors are written by the problem package.
+package response
+
+import (
+	"encoding/json"
+	"marketplace-service/internal/logger"
+	"net/http"
+)
+
+// JSON writes v with the status. The headers are sent by then, so a failed
+// encoding is only logged.
+func JSON(w http.ResponseWriter, l logger.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		l.Info(err)
+	}
+}
@@ -0,0 +1,319 @@
+package reviews
+
+import (
+	"errors"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/model"
+	"marketplace-service/internal/notifications"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"net/http"
+	"time"
+)
+
+type handler struct {
+	db         store.ReviewsStore
+	users      store.UserStore
+	feed       store.FeedInvalidator
+	notifier   notifications.Notifier
+	logger     logger.Logger
+	token      *token.Service
+	editWindow time.Duration
+	maxLimit   int
+}
+
+type ReviewPostRequest struct {
+	Rating int    `json:"rating" example:"5" validate:"required,min=1,max=5"`
+	Text   string `json:"text" example:"Диван как в описании, продавец помог с погрузкой." validate:"required,min=1,max=2000"`
+}
+
+type ReplyPostRequest struct {
+	Text string `json:"text" example:"Спасибо за покупку!" validate:"required,min=1,max=2000"`
+}
+
+func NewHandler(db store.ReviewsStore, users store.UserStore, feed store.FeedInvalidator, notifier notifications.Notifier, logger logger.Logger, token *token.Service, editWindow time.Duration, maxLimit int) *handler {
+	return &handler{
+		db:         db,
+		users:      users,
+		feed:       feed,
+		notifier:   notifier,
+		logger:     logger,
+		token:      token,
+		editWindow: editWindow,
+		maxLimit:   maxLimit,
+	}
+}
+
+func (h *handler) RegisterService(mux *http.ServeMux) {
+	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
+
+	getReviewsHandler := middleware.Chain(
+		http.HandlerFunc(h.getReviews),
+		middleware.BindQuery[middleware.PageQuery](middleware.AtMost("limit", float64(h.maxLimit))),
+		loggerMiddleware,
+	)
+
+	mux.Handle("GET /api/v1/users/{username}/reviews", getReviewsHandler)
+	mux.Handle("POST /api/v1/users/{username}/reviews", loggerMiddleware(middleware.AuthMiddleware(h.token, middleware.BindBody[ReviewPostRequest](h.createReview))))
+	mux.Handle("PATCH /api/v1/reviews/{id}", loggerMiddleware(middleware.AuthMiddleware(h.token, middleware.BindBody[ReviewPostRequest](h.updateReview))))
+	mux.Handle("POST /api/v1/reviews/{id}/reply", loggerMiddleware(middleware.AuthMiddleware(h.token, middleware.BindBody[ReplyPostRequest](h.replyToReview))))
+}
+
+// loadReview fetches the review from the path. It writes the error response itself.
+func (h *handler) loadReview(w http.ResponseWriter, r *http.Request) (*model.Review, bool) {
+	id, ok := middleware.PathId(r)
+	if !ok {
+		problem.Error(w, "Invalid review id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	review, err := h.db.GetReviewById(id)
+	if err != nil {
+		if errors.Is(err, store.ErrReviewNotFound) {
+			problem.Error(w, "Review not found", http.StatusNotFound)
+			return nil, false
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return review, true
+}
+
+// GetReviews gets reviews about a seller
+// @Summary      Get seller reviews
+// @Description  Get a paginated list of reviews left for the user, newest first. This endpoint is public.
+// @Tags         Reviews
+// @Produce      json
+// @Param        username path       string true   "Username of the seller"
+// @Param        query    query      middleware.PageQuery  false  "Page"
+// @Success      200      {array}    model.Review
+// @Failure      400 {object}  problem.Problem  "Invalid page or limit parameter"
+// @Failure      404 {object}  problem.Problem  "User not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/users/{username}/reviews [get]
+func (h *handler) getReviews(w http.ResponseWriter, r *http.Request) {
+	query := middleware.Query[middleware.PageQuery](r)
+
+	sellerId, err := h.users.GetUserIdByUsername(r.PathValue("username"))
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			problem.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	reviews, err := h.db.GetReviewsBySeller(sellerId, query.Page, query.Limit)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, reviews)
+}
+
+// Review creation
+// @Summary      Review a seller
+// @Description  Leave a rating and a review for the seller. Only buyers who have had a conversation about one of the seller's announcements may do it, once per seller.
+// @Tags         Reviews
+// @Accept       json
+// @Produce      json
+// @Param        username path   string             true  "Username of the seller"
+// @Param        request  body   ReviewPostRequest  true  "Review"
+// @Param        Idempotency-Key  header  string  false  "Repeating the request with the same key returns the first response"
+// @Success      201 {object} model.Review "Review successfully created"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload or own profile"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "No conversation with the seller"
+// @Failure      404 {object}  problem.Problem  "User not found"
+// @Failure      409 {object}  problem.Problem  "Review already exists"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/users/{username}/reviews [post]
+// @Security     Bearer
+func (h *handler) createReview(w http.ResponseWriter, r *http.Request) {
+	rpr := middleware.Body[ReviewPostRequest](r)
+
+	userId, _ := middleware.GetUserId(r)
+	buyerId := int64(userId)
+
+	sellerId, err := h.users.GetUserIdByUsername(r.PathValue("username"))
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			problem.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if sellerId == buyerId {
+		problem.Error(w, "You can not review yourself", http.StatusBadRequest)
+		return
+	}
+
+	talked, err := h.db.HasConversation(buyerId, sellerId)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !talked {
+		problem.Error(w, "Only buyers who contacted the seller can review them", http.StatusForbidden)
+		return
+	}
+
+	review := &model.Review{
+		SellerId: sellerId,
+		BuyerId:  buyerId,
+		Rating:   rpr.Rating,
+		Text:     rpr.Text,
+	}
+
+	if err := h.db.CreateReview(review); err != nil {
+		if errors.Is(err, store.ErrReviewAlreadyExists) {
+			problem.Error(w, "You have already reviewed this seller", http.StatusConflict)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// The feed shows the rating of the seller.
+	h.feed.Invalidate()
+
+	review, err = h.db.GetReviewById(review.Id)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.notifier.Notify(sellerId, "review.new", map[string]any{
+		"review_id": review.Id,
+		"rating":    review.Rating,
+	})
+	if err != nil {
+		h.logger.Info(err)
+	}
+
+	response.JSON(w, h.logger, http.StatusCreated, review)
+}
+
+// Review editing
+// @Summary      Edit a review
+// @Description  Change the rating and the text of an own review. It is possible only for a limited time after the review was created.
+// @Tags         Reviews
+// @Accept       json
+// @Produce      json
+// @Param        id       path   int                true  "Review id"
+// @Param        request  body   ReviewPostRequest  true  "Review"
+// @Success      200 {object} model.Review "Review successfully updated"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "Not the author of the review or the edit window is over"
+// @Failure      404 {object}  problem.Problem  "Review not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/reviews/{id} [patch]
+// @Security     Bearer
+func (h *handler) updateReview(w http.ResponseWriter, r *http.Request) {
+	review, ok := h.loadReview(w, r)
+	if !ok {
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	if review.BuyerId != int64(userId) {
+		problem.Error(w, "Only the author can edit the review", http.StatusForbidden)
+		return
+	}
+
+	if time.Since(review.Date) > h.editWindow {
+		problem.Error(w, "The review can no longer be edited", http.StatusForbidden)
+		return
+	}
+
+	rpr := middleware.Body[ReviewPostRequest](r)
+
+	review.Rating = rpr.Rating
+	review.Text = rpr.Text
+
+	if err := h.db.UpdateReview(review); err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.feed.Invalidate()
+
+	response.JSON(w, h.logger, http.StatusOK, review)
+}
+
+// Review reply
+// @Summary      Reply to a review
+// @Description  The seller may reply once to each review left for them.
+// @Tags         Reviews
+// @Accept       json
+// @Produce      json
+// @Param        id       path   int               true  "Review id"
+// @Param        request  body   ReplyPostRequest  true  "Reply"
+// @Param        Idempotency-Key  header  string  false  "Repeating the request with the same key returns the first response"
+// @Success      201 {object} model.Review "Reply successfully created"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      403 {object}  problem.Problem  "The review is not about the authorized user"
+// @Failure      404 {object}  problem.Problem  "Review not found"
+// @Failure      409 {object}  problem.Problem  "Review already has a reply"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/reviews/{id}/reply [post]
+// @Security     Bearer
+func (h *handler) replyToReview(w http.ResponseWriter, r *http.Request) {
+	review, ok := h.loadReview(w, r)
+	if !ok {
+		return
+	}
+
+	userId, _ := middleware.GetUserId(r)
+	if review.SellerId != int64(userId) {
+		problem.Error(w, "Only the reviewed seller can reply", http.StatusForbidden)
+		return
+	}
+
+	rpr := middleware.Body[ReplyPostRequest](r)
+
+	if err := h.db.ReplyToReview(review.Id, rpr.Text); err != nil {
+		if errors.Is(err, store.ErrReviewAlreadyReplied) {
+			problem.Error(w, "The review already has a reply", http.StatusConflict)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	review, err := h.db.GetReviewById(review.Id)
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.notifier.Notify(review.BuyerId, "review.reply", map[string]any{
+		"review_id": review.Id,
+	})
+	if err != nil {
+		h.logger.Info(err)
+	}
+
+	response.JSON(w, h.logger, http.StatusCreated, review)
+}
@@ -1,17 +1,80 @@
 package store
 
-import "marketplace-service/internal/model"
+import (
+	"time"
 
-var ValidSortColumns = map[string]string {
-	"price_asc":  "price ASC",
-	"price_desc": "price DESC",
-	"date_asc":   "created_at ASC",
-	"date_desc":  "created_at DESC",
+	"marketplace-service/internal/model"
+)
+
+// SortOrder is one of the orders the announcement list can be sorted by.
+type SortOrder string
+
+const (
+	SortPriceAsc  SortOrder = "price_asc"
+	SortPriceDesc SortOrder = "price_desc"
+	SortDateAsc   SortOrder = "date_asc"
+	SortDateDesc  SortOrder = "date_desc"
+	SortPriceDrop SortOrder = "price_drop"
+	SortDistance  SortOrder = "distance"
+)
+
+// Nearby limits the list to the announcements around the point. A zero
+// radius only computes the distances without filtering.
+type Nearby struct {
+	Lat      float64
+	Lon      float64
+	RadiusKm float64
 }
 
-const DefaultSorting = "date_desc"
+// AnnouncementFilter selects the announcements of the public list. Prices are
+// inclusive bounds, the city is compared case-insensitively.
+type AnnouncementFilter struct {
+	MinPrice int
+	MaxPrice int
+	Status   string
+	City     string
+	Near     *Nearby
+	Category *CategoryFilter
+}
+
+// AnnouncementQuery is a page of the public list. CurrentUserId is 0 for
+// anonymous users.
+type AnnouncementQuery struct {
+	Filter        AnnouncementFilter
+	Sort          []SortOrder
+	Page          int
+	Limit         int
+	CurrentUserId int
+}
 
 type AnnouncementsStore interface  {
 	CreateAnnouncement(an *model.Announcement) error
-	GetAnnouncementsByPage(page, limit, currentUserId int, sortBy string, maxPrice, minPrice int) ([]model.Announcement, error)
+	GetAnnouncementsByPage(q AnnouncementQuery) ([]model.Announcement, error)
+	GetAnnouncementById(id int64, currentUserId int) (*model.Announcement, error)
+	GetAnnouncementsByOwner(username string, page, limit, currentUserId int) ([]model.Announcement, error)
+	UpdateAnnouncement(an *model.Announcement) (int32, error)
+	RenewAnnouncement(id int64, expiresAt time.Time) error
+	GetDraftsByOwner(userId int64, page, limit int) ([]model.Announcement, error)
+	PublishAnnouncement(id int64, expiresAt time.Time) error
+	DeleteAnnouncement(id, version int64) error
+}
+
+type ExpirationStore interface {
+	ArchiveExpiredAnnouncements() ([]model.Announcement, error)
+	ArchiveAnnouncement(id int64) error
+	MarkExpiringAnnouncements(before time.Time) ([]model.Announcement, error)
+}
+
+// PublishingStore publishes scheduled drafts. The publishable callback decides
+// whether a due draft passes the full validation; drafts that do not are
+// unscheduled and returned as rejected. An error of the callback stops the
+// publishing and leaves the drafts as they are.
+type PublishingStore interface {
+	PublishDueDrafts(limit int, expiresAt time.Time, publishable func(model.Announcement) (bool, error)) (published, rejected []model.Announcement, err error)
+}
+
+type PriceAlertsStore interface {
+	SubscribeToPriceDrops(userId, announcementId int64) error
+	UnsubscribeFromPriceDrops(userId, announcementId int64) error
+	GetPriceDropSubscribers(announcementId int64) ([]int64, error)
 }
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"encoding/json"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/model"
+)
+
+// CachedAnnouncementsStore keeps the pages of the public list in a
+// ResponseCache in front of another AnnouncementsStore. Pages are cached
+// without the current user and is_owner is filled in after the lookup, so one
+// user never gets the fields of another.
+//
+// Changes made through the store clear the cache. The code that changes the
+// list past the store (the sweeper, the scheduler, reservations, reviews of
+// the owner) calls Invalidate, other processes call NotifyFeedChanged.
+type CachedAnnouncementsStore struct {
+	next   AnnouncementsStore
+	cache  ResponseCache
+	logger logger.Logger
+	ttl    time.Duration
+
+	// generation changes on every invalidation, so a page read before it is
+	// not put back into the cache after it.
+	generation atomic.Uint64
+}
+
+// FeedInvalidator drops the cached pages of the public list after a change
+// made past the CachedAnnouncementsStore.
+type FeedInvalidator interface {
+	Invalidate()
+}
+
+// NoFeedCache is the FeedInvalidator of the service running without the cache.
+type NoFeedCache struct{}
+
+func (NoFeedCache) Invalidate() {}
+
+func NewCachedAnnouncementsStore(next AnnouncementsStore, cache ResponseCache, l logger.Logger, ttl time.Duration) *CachedAnnouncementsStore {
+	return &CachedAnnouncementsStore{
+		next:   next,
+		cache:  cache,
+		logger: l,
+		ttl:    ttl,
+	}
+}
+
+// pageKey is the normalized query without the current user.
+type pageKey struct {
+	MinPrice   int
+	MaxPrice   int
+	Status     string
+	City       string
+	Near       *Nearby
+	Category   *int64
+	Attributes []AttributeFilter
+	Sort       []SortOrder
+	Page       int
+	Limit      int
+}
+
+func cacheKey(q AnnouncementQuery) (string, error) {
+	key := pageKey{
+		MinPrice: q.Filter.MinPrice,
+		MaxPrice: q.Filter.MaxPrice,
+		Status:   q.Filter.Status,
+		City:     q.Filter.City,
+		Near:     q.Filter.Near,
+		Sort:     q.Sort,
+		Page:     q.Page,
+		Limit:    q.Limit,
+	}
+	if q.Filter.Category != nil {
+		key.Category = &q.Filter.Category.Id
+		key.Attributes = slices.Clone(q.Filter.Category.Attributes)
+		slices.SortFunc(key.Attributes, func(a, b AttributeFilter) int {
+			return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Op, b.Op))
+		})
+	}
+
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return "announcements:" + string(b), nil
+}
+
+func (s *CachedAnnouncementsStore) GetAnnouncementsByPage(q AnnouncementQuery) ([]model.Announcement, error) {
+	key, err := cacheKey(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok, err := s.cache.Get(key); err != nil {
+		s.logger.Info(err)
+	} else if ok {
+		var announcements []model.Announcement
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&announcements); err == nil {
+			return withOwner(announcements, q.CurrentUserId), nil
+		}
+		s.logger.Info(err)
+	}
+
+	generation := s.generation.Load()
+
+	anonymous := q
+	anonymous.CurrentUserId = 0
+	announcements, err := s.next.GetAnnouncementsByPage(anonymous)
+	if err != nil {
+		return nil, err
+	}
+
+	var value bytes.Buffer
+	if err := gob.NewEncoder(&value).Encode(announcements); err != nil {
+		s.logger.Info(err)
+	} else if s.generation.Load() == generation {
+		if err := s.cache.Set(key, value.Bytes(), s.ttl); err != nil {
+			s.logger.Info(err)
+		}
+	}
+
+	return withOwner(announcements, q.CurrentUserId), nil
+}
+
+// withOwner fills in is_owner the way the queries do: it is set only for an
+// authenticated user.
+func withOwner(announcements []model.Announcement, currentUserId int) []model.Announcement {
+	for i := range announcements {
+		if currentUserId > 0 {
+			isOwner := announcements[i].UserId == int64(currentUserId)
+			announcements[i].IsOwner = &isOwner
+		} else {
+			announcements[i].IsOwner = nil
+		}
+	}
+	return announcements
+}
+
+// Invalidate clears the cache.
+func (s *CachedAnnouncementsStore) Invalidate() {
+	s.generation.Add(1)
+	if err := s.cache.Clear(); err != nil {
+		s.logger.Info(err)
+	}
+}
+
+// invalidate clears the cache after a successful change.
+func (s *CachedAnnouncementsStore) invalidate(err error) error {
+	if err != nil {
+		return err
+	}
+
+	s.Invalidate()
+	return nil
+}
+
+func (s *CachedAnnouncementsStore) CreateAnnouncement(an *model.Announcement) error {
+	return s.invalidate(s.next.CreateAnnouncement(an))
+}
+
+func (s *CachedAnnouncementsStore) GetAnnouncementById(id int64, currentUserId int) (*model.Announcement, error) {
+	return s.next.GetAnnouncementById(id, currentUserId)
+}
+
+func (s *CachedAnnouncementsStore) GetAnnouncementsByOwner(username string, page, limit, currentUserId int) ([]model.Announcement, error) {
+	return s.next.GetAnnouncementsByOwner(username, page, limit, currentUserId)
+}
+
+func (s *CachedAnnouncementsStore) UpdateAnnouncement(an *model.Announcement) (int32, error) {
+	oldPrice, err := s.next.UpdateAnnouncement(an)
+	return oldPrice, s.invalidate(err)
+}
+
+func (s *CachedAnnouncementsStore) RenewAnnouncement(id int64, expiresAt time.Time) error {
+	return s.invalidate(s.next.RenewAnnouncement(id, expiresAt))
+}
+
+func (s *CachedAnnouncementsStore) GetDraftsByOwner(userId int64, page, limit int) ([]model.Announcement, error) {
+	return s.next.GetDraftsByOwner(userId, page, limit)
+}
+
+func (s *CachedAnnouncementsStore) PublishAnnouncement(id int64, expiresAt time.Time) error {
+	return s.invalidate(s.next.PublishAnnouncement(id, expiresAt))
+}
+
+func (s *CachedAnnouncementsStore) DeleteAnnouncement(id, version int64) error {
+	return s.invalidate(s.next.DeleteAnnouncement(id, version))
+}
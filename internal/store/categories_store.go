@@ -0,0 +1,30 @@
+package store
+
+import "marketplace-service/internal/model"
+
+const (
+	AttributeEquals   = "eq"
+	AttributeMin      = "min"
+	AttributeMax      = "max"
+	AttributeContains = "contains"
+)
+
+// AttributeFilter is a condition on one attribute of the announcements. The
+// name and the value are always passed to the database as parameters.
+type AttributeFilter struct {
+	Name  string
+	Op    string
+	Value any
+}
+
+// CategoryFilter limits the list to one category and its attribute values.
+type CategoryFilter struct {
+	Id         int64
+	Attributes []AttributeFilter
+}
+
+type CategoriesStore interface {
+	GetCategories() ([]model.Category, error)
+	GetCategoryBySlug(slug string) (*model.Category, error)
+	GetCategoryById(id int64) (*model.Category, error)
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"marketplace-service/internal/logger"
+)
+
+// Cleaner is a store that forgets the records which no longer matter.
+type Cleaner interface {
+	Cleanup() error
+}
+
+// RunCleanup cleans the store every interval until the context is done.
+func RunCleanup(ctx context.Context, c Cleaner, interval time.Duration, l logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.Cleanup(); err != nil {
+			l.Info(err)
+		}
+	}
+}
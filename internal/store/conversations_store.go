@@ -0,0 +1,21 @@
+package store
+
+import "marketplace-service/internal/model"
+
+// GetOrCreateConversation and CreateMessage return ErrUsersBlocked and create
+// nothing when the participants blocked one another.
+type ConversationsStore interface {
+	GetOrCreateConversation(announcementId, buyerId int64) (*model.Conversation, error)
+	GetConversationById(id, currentUserId int64) (*model.Conversation, error)
+	GetConversationsByPage(userId int64, page, limit int) ([]model.Conversation, error)
+	GetSellerInbox(sellerId int64, page, limit int) ([]model.ListingInbox, error)
+	CountUnreadMessages(userId int64) (int, error)
+
+	CreateMessage(m *model.Message) error
+	GetMessagesByPage(conversationId int64, page, limit int) ([]model.Message, error)
+	GetMessagesAfter(userId, lastId int64, limit int) ([]model.Message, error)
+	MarkConversationRead(conversationId, readerId int64) (int64, error)
+
+	BlockUser(blockerId, blockedId int64) error
+	UnblockUser(blockerId, blockedId int64) error
+}
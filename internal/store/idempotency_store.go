@@ -0,0 +1,17 @@
+package store
+
+import (
+	"time"
+
+	"marketplace-service/internal/model"
+)
+
+type IdempotencyStore interface {
+	// Reserve claims the key of the user for a new request. If the key is
+	// already taken, it returns false and the request stored under it. Keys
+	// older than ttl and requests not completed within lockTimeout are
+	// claimed again.
+	Reserve(userId int64, key, fingerprint string, ttl, lockTimeout time.Duration) (bool, *model.IdempotentRequest, error)
+	Complete(userId int64, key string, request *model.IdempotentRequest) error
+	Release(userId int64, key string) error
+}
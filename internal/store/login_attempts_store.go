@@ -0,0 +1,19 @@
+package store
+
+import "time"
+
+// LoginAttemptsStore counts failed logins per key, e.g. a username or a client
+// address, and keeps the time the key is locked until.
+type LoginAttemptsStore interface {
+	// RegisterFailure counts the failure and returns the number of failures
+	// of the key within the window. The window runs from the last failure or
+	// from the end of the lock, whichever is later, so that a lock longer
+	// than the window does not start the count over.
+	RegisterFailure(key string, window time.Duration) (int, error)
+	// LockUntil locks the key. A lock never gets shorter.
+	LockUntil(key string, until time.Time) error
+	// LockedUntil returns the latest lock among the keys, zero if none of
+	// them is locked.
+	LockedUntil(keys ...string) (time.Time, error)
+	Reset(key string) error
+}
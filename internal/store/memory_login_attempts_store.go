@@ -0,0 +1,98 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+type loginAttempts struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+}
+
+// MemoryLoginAttemptsStore keeps the counters in the process. It protects a
+// single instance of the service only. Cleanup forgets the keys whose
+// failures no longer count.
+type MemoryLoginAttemptsStore struct {
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+	// window is the longest window the failures were counted in.
+	window time.Duration
+}
+
+func NewMemoryLoginAttemptsStore() *MemoryLoginAttemptsStore {
+	return &MemoryLoginAttemptsStore{attempts: make(map[string]*loginAttempts)}
+}
+
+func (s *MemoryLoginAttemptsStore) RegisterFailure(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.window = max(s.window, window)
+
+	a, ok := s.attempts[key]
+	if !ok {
+		a = &loginAttempts{}
+		s.attempts[key] = a
+	}
+	if a.expired(now, window) {
+		a.failures = 0
+	}
+
+	a.failures++
+	a.lastFailureAt = now
+
+	return a.failures, nil
+}
+
+// expired reports whether the window has passed since the last failure and
+// since the end of the lock.
+func (a *loginAttempts) expired(now time.Time, window time.Duration) bool {
+	return now.Sub(a.lastFailureAt) > window && now.Sub(a.lockedUntil) > window
+}
+
+func (s *MemoryLoginAttemptsStore) Cleanup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, a := range s.attempts {
+		if a.expired(now, s.window) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryLoginAttemptsStore) LockUntil(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.attempts[key]; ok && until.After(a.lockedUntil) {
+		a.lockedUntil = until
+	}
+	return nil
+}
+
+func (s *MemoryLoginAttemptsStore) LockedUntil(keys ...string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var until time.Time
+	for _, key := range keys {
+		if a, ok := s.attempts[key]; ok && a.lockedUntil.After(until) {
+			until = a.lockedUntil
+		}
+	}
+	return until, nil
+}
+
+func (s *MemoryLoginAttemptsStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	period    time.Duration
+}
+
+// MemoryRateLimitStore keeps the buckets in the process. Every instance of the
+// service limits the requests it gets on its own.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, capacity int, period time.Duration) (bool, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(capacity), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.period = period
+
+	refill := now.Sub(b.updatedAt).Seconds() * float64(capacity) / period.Seconds()
+	b.tokens = min(float64(capacity), b.tokens+refill)
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return false, b.tokens, nil
+	}
+
+	b.tokens--
+	return true, b.tokens, nil
+}
+
+// prune forgets the buckets that have refilled completely, at most once a
+// minute.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) > b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
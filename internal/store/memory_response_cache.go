@@ -0,0 +1,86 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryResponseCache is a least recently used cache in the process. It holds
+// at most size values and evicts the least recently used one to make room.
+type MemoryResponseCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func NewMemoryResponseCache(size int) *MemoryResponseCache {
+	return &MemoryResponseCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryResponseCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (c *MemoryResponseCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size <= 0 {
+		return nil
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	for c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expiresAt: expiresAt})
+	return nil
+}
+
+func (c *MemoryResponseCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	return nil
+}
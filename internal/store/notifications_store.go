@@ -0,0 +1,13 @@
+package store
+
+import "marketplace-service/internal/model"
+
+const DefaultNotificationFilter = "all"
+
+type NotificationsStore interface {
+	CreateNotification(n *model.Notification) error
+	GetNotificationsByPage(userId, page, limit int, filter string) ([]model.Notification, error)
+	GetNotificationsAfter(userId int, lastId int64, limit int) ([]model.Notification, error)
+	MarkNotificationRead(userId int, id int64) error
+	MarkAllNotificationsRead(userId int) error
+}
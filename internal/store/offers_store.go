@@ -0,0 +1,16 @@
+package store
+
+import (
+	"time"
+
+	"marketplace-service/internal/model"
+)
+
+type OffersStore interface {
+	CreateOffer(o *model.Offer) error
+	GetOfferById(id int64) (*model.Offer, error)
+	GetOffersByAnnouncement(announcementId, buyerId int64) ([]model.Offer, error)
+	CounterOffer(id int64, amount int32, expiresAt time.Time) error
+	DeclineOffer(id int64, expectedStatus string) error
+	AcceptOffer(id int64, expectedStatus string) ([]model.Offer, error)
+}
@@ -2,8 +2,17 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"marketplace-service/internal/model"
+	"time"
+)
+
+var (
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+	ErrAnnouncementNotDraft = errors.New("announcement is not a draft")
+	ErrVersionMismatch      = errors.New("announcement has been changed")
 )
 
 type PostgresAnnouncementsStore struct {
@@ -15,34 +24,105 @@ func NewPostgresAnnouncementsStore(db *sql.DB) *PostgresAnnouncementsStore {
 }
 
 func (s *PostgresAnnouncementsStore) CreateAnnouncement(an *model.Announcement) error {
-	query := `INSERT INTO announcements(user_id, title, text, image_url, price) VALUES($1, $2, $3, $4, $5) RETURNING id, created_at`
-	err := s.DB.QueryRow(query, an.UserId, an.Article, an.Text, an.ImageAddress, an.CostRubles).Scan(&an.Id, &an.Date)
+	query := `
+		INSERT INTO announcements(user_id, title, text, image_url, price, status, expires_at, publish_at, city, latitude, longitude,
+		category_id, attributes)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id, created_at
+	`
+	err := s.DB.QueryRow(query, an.UserId, an.Article, an.Text, an.ImageAddress, an.CostRubles, an.Status, an.ExpiresAt, an.PublishAt,
+		an.City, an.Latitude, an.Longitude, an.CategoryId, attributesParam(an.Attributes)).Scan(&an.Id, &an.Date)
 	return err
 }
 
+// announcementColumns selects announcements with their owner, the owner's
+// rating and the last price change. The id of the current user is $1. The
+// distance expression is filled in by selectAnnouncements.
+const announcementColumns = `
+	SELECT announcements.id, announcements.user_id, users.username, title, text, image_url, price, status, announcements.version, expires_at, publish_at,
+	COALESCE(city, ''), latitude, longitude, %s AS distance_km,
+	announcements.category_id, COALESCE(categories.slug, ''), announcements.attributes,
+	CASE WHEN $1 > 0 THEN (announcements.user_id = $1) ELSE NULL END AS is_owner,
+	(SELECT ROUND(AVG(rating), 2)::float8 FROM reviews WHERE reviews.seller_id = announcements.user_id) AS owner_rating,
+	last_change.old_price, last_change.changed_at,
+	announcements.created_at, COALESCE(announcements.updated_at, announcements.created_at)
+	FROM announcements
+	JOIN users ON announcements.user_id = users.id
+	LEFT JOIN categories ON announcements.category_id = categories.id
+	LEFT JOIN LATERAL (
+		SELECT old_price, changed_at FROM price_history
+		WHERE price_history.announcement_id = announcements.id
+		ORDER BY price_history.id DESC
+		LIMIT 1
+	) last_change ON true
+`
+
+// attributesParam passes the attributes to a JSONB column.
+func attributesParam(attributes json.RawMessage) []byte {
+	if len(attributes) == 0 {
+		return []byte("{}")
+	}
+	return attributes
+}
+
+// noDistance is used by the queries that are not made around a point.
+const noDistance = "NULL::float8"
+
+func selectAnnouncements(distance string) string {
+	return fmt.Sprintf(announcementColumns, distance)
+}
+
+func scanAnnouncement(row interface{ Scan(...any) error }, an *model.Announcement) error {
+	var isOwner sql.NullBool
+	var attributes []byte
+
+	if err := row.Scan(
+		&an.Id,
+		&an.UserId,
+		&an.OwnerUsername,
+		&an.Article,
+		&an.Text,
+		&an.ImageAddress,
+		&an.CostRubles,
+		&an.Status,
+		&an.Version,
+		&an.ExpiresAt,
+		&an.PublishAt,
+		&an.City,
+		&an.Latitude,
+		&an.Longitude,
+		&an.DistanceKm,
+		&an.CategoryId,
+		&an.Category,
+		&attributes,
+		&isOwner,
+		&an.OwnerRating,
+		&an.PreviousPrice,
+		&an.PriceChangedAt,
+		&an.Date,
+		&an.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	an.Attributes = attributes
+
+	if isOwner.Valid {
+		an.IsOwner = &isOwner.Bool
+	} else {
+		an.IsOwner = nil
+	}
+
+	return nil
+}
+
 func extractAnnouncements(rows *sql.Rows) ([]model.Announcement, error) {
 	defer rows.Close()
 
 	var announcements []model.Announcement
 	for rows.Next() {
 		var an model.Announcement
-		var isOwner sql.NullBool
-
-		if err := rows.Scan(
-			&an.OwnerUsername,
-			&an.Article,
-			&an.Text,
-			&an.ImageAddress,
-			&an.CostRubles,
-			&isOwner,
-		); err != nil {
-			return nil, err
-		}
 
-		if isOwner.Valid {
-			an.IsOwner = &isOwner.Bool
-		} else {
-			an.IsOwner = nil
+		if err := scanAnnouncement(rows, &an); err != nil {
+			return nil, err
 		}
 
 		announcements = append(announcements, an)
@@ -55,23 +135,362 @@ func extractAnnouncements(rows *sql.Rows) ([]model.Announcement, error) {
 	return announcements, nil
 }
 
-func (s *PostgresAnnouncementsStore) GetAnnouncementsByPage(page, limit, currentUserId int, sortBy string, minPrice, maxPrice int) ([]model.Announcement, error) {
-	query := fmt.Sprintf(`
-		SELECT users.username, title, text, image_url, price,
-		CASE WHEN $3 > 0 THEN (user_id = $3) ELSE NULL END AS is_owner
-		FROM announcements
-		JOIN users ON announcements.user_id = users.id
-		WHERE price >= $4 AND price <= $5
-		ORDER BY %s
-		LIMIT $1
-		OFFSET $2
-	`, sortBy)
+func (s *PostgresAnnouncementsStore) GetAnnouncementsByPage(q AnnouncementQuery) ([]model.Announcement, error) {
+	query, args, err := buildAnnouncementQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractAnnouncements(rows)
+}
+
+func (s *PostgresAnnouncementsStore) GetAnnouncementsByOwner(username string, page, limit, currentUserId int) ([]model.Announcement, error) {
+	var exists bool
+	if err := s.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, username).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	query := selectAnnouncements(noDistance) + `
+		WHERE users.username = $2 AND status IN ('active', 'reserved')
+		AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY announcements.created_at DESC
+		LIMIT $3
+		OFFSET $4
+	`
+	offset := (page - 1) * limit
+
+	rows, err := s.DB.Query(query, currentUserId, username, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractAnnouncements(rows)
+}
+
+func (s *PostgresAnnouncementsStore) GetAnnouncementById(id int64, currentUserId int) (*model.Announcement, error) {
+	an := &model.Announcement{}
+
+	err := scanAnnouncement(s.DB.QueryRow(selectAnnouncements(noDistance)+`WHERE announcements.id = $2`, currentUserId, id), an)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, err
+	}
+
+	return an, nil
+}
+
+// UpdateAnnouncement saves the editable fields of the announcement and records
+// the change of its price in the price history unless it is still a draft. It
+// returns the price the announcement had before. The announcement is saved
+// only if it still has the version it was read with; the version is advanced.
+func (s *PostgresAnnouncementsStore) UpdateAnnouncement(an *model.Announcement) (int32, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var oldPrice int32
+	var status string
+	var version int64
+	err = tx.QueryRow(`SELECT price, status, version FROM announcements WHERE id = $1 FOR UPDATE`, an.Id).Scan(&oldPrice, &status, &version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrAnnouncementNotFound
+		}
+		return 0, err
+	}
+	if version != an.Version {
+		return 0, ErrVersionMismatch
+	}
+
+	query := `
+		UPDATE announcements SET title = $1, text = $2, image_url = $3, price = $4, publish_at = $5,
+		city = $6, latitude = $7, longitude = $8, category_id = $9, attributes = $10,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $11
+	`
+	_, err = tx.Exec(query, an.Article, an.Text, an.ImageAddress, an.CostRubles, an.PublishAt, an.City, an.Latitude, an.Longitude,
+		an.CategoryId, attributesParam(an.Attributes), an.Id)
+	if err != nil {
+		return 0, err
+	}
+
+	if oldPrice != an.CostRubles && status != model.AnnouncementDraft {
+		history := `INSERT INTO price_history(announcement_id, old_price, new_price) VALUES($1, $2, $3)`
+		if _, err := tx.Exec(history, an.Id, oldPrice, an.CostRubles); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	an.Version++
+	return oldPrice, nil
+}
+
+// RenewAnnouncement moves the expiration time of the announcement and brings
+// it back to the feed if it has been archived.
+func (s *PostgresAnnouncementsStore) RenewAnnouncement(id int64, expiresAt time.Time) error {
+	query := `
+		UPDATE announcements SET expires_at = $1, expiry_notified_at = NULL,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP,
+		status = CASE WHEN status = 'archived' THEN 'active' ELSE status END
+		WHERE id = $2
+	`
+	res, err := s.DB.Exec(query, expiresAt, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAnnouncementNotFound
+	}
+	return nil
+}
+
+func (s *PostgresAnnouncementsStore) GetDraftsByOwner(userId int64, page, limit int) ([]model.Announcement, error) {
+	query := selectAnnouncements(noDistance) + `
+		WHERE announcements.user_id = $2 AND status = 'draft'
+		ORDER BY announcements.updated_at DESC
+		LIMIT $3
+		OFFSET $4
+	`
 	offset := (page - 1) * limit
 
-	rows, err := s.DB.Query(query, limit, offset, currentUserId, minPrice, maxPrice)
+	rows, err := s.DB.Query(query, userId, userId, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 
 	return extractAnnouncements(rows)
 }
+
+const publishDraft = `
+	UPDATE announcements SET status = 'active', publish_at = NULL, expires_at = $1, version = version + 1,
+	created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND status = 'draft'
+`
+
+// PublishAnnouncement turns the draft into an active announcement dated now.
+func (s *PostgresAnnouncementsStore) PublishAnnouncement(id int64, expiresAt time.Time) error {
+	res, err := s.DB.Exec(publishDraft, expiresAt, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAnnouncementNotDraft
+	}
+	return nil
+}
+
+// PublishDueDrafts publishes up to limit drafts whose publish time has come.
+// The drafts are locked with SKIP LOCKED, so concurrent callers never get the
+// same draft and every draft is published exactly once.
+func (s *PostgresAnnouncementsStore) PublishDueDrafts(limit int, expiresAt time.Time, publishable func(model.Announcement) (bool, error)) ([]model.Announcement, []model.Announcement, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, user_id, title, COALESCE(text, ''), COALESCE(image_url, ''), price, publish_at, category_id, attributes
+		FROM announcements
+		WHERE status = 'draft' AND publish_at <= CURRENT_TIMESTAMP
+		ORDER BY publish_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var due []model.Announcement
+	for rows.Next() {
+		an := model.Announcement{Status: model.AnnouncementDraft}
+		var attributes []byte
+		if err := rows.Scan(&an.Id, &an.UserId, &an.Article, &an.Text, &an.ImageAddress, &an.CostRubles, &an.PublishAt,
+			&an.CategoryId, &attributes); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		an.Attributes = attributes
+		due = append(due, an)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var published, rejected []model.Announcement
+	for _, an := range due {
+		ok, err := publishable(an)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			if _, err := tx.Exec(`UPDATE announcements SET publish_at = NULL, version = version + 1 WHERE id = $1`, an.Id); err != nil {
+				return nil, nil, err
+			}
+			rejected = append(rejected, an)
+			continue
+		}
+
+		if _, err := tx.Exec(publishDraft, expiresAt, an.Id); err != nil {
+			return nil, nil, err
+		}
+		an.Status = model.AnnouncementActive
+		an.ExpiresAt = &expiresAt
+		published = append(published, an)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return published, rejected, nil
+}
+
+// ArchiveExpiredAnnouncements archives the announcements whose time is over
+// and returns them. Every announcement is returned by only one call, even if
+// several instances of the service sweep at the same time.
+func (s *PostgresAnnouncementsStore) ArchiveExpiredAnnouncements() ([]model.Announcement, error) {
+	query := `
+		UPDATE announcements SET status = 'archived', version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE status IN ('active', 'reserved') AND expires_at <= CURRENT_TIMESTAMP
+		RETURNING id, user_id, title, expires_at
+	`
+	return s.sweep(query)
+}
+
+// ArchiveAnnouncement archives an active or reserved announcement before its
+// time. Other announcements are not found.
+func (s *PostgresAnnouncementsStore) ArchiveAnnouncement(id int64) error {
+	query := `
+		UPDATE announcements SET status = 'archived', version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status IN ('active', 'reserved')
+	`
+	res, err := s.DB.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAnnouncementNotFound
+	}
+	return nil
+}
+
+// MarkExpiringAnnouncements returns the announcements expiring before the
+// given time whose owners have not been warned yet, and marks them as warned.
+func (s *PostgresAnnouncementsStore) MarkExpiringAnnouncements(before time.Time) ([]model.Announcement, error) {
+	query := `
+		UPDATE announcements SET expiry_notified_at = CURRENT_TIMESTAMP
+		WHERE status IN ('active', 'reserved') AND expiry_notified_at IS NULL
+		AND expires_at > CURRENT_TIMESTAMP AND expires_at <= $1
+		RETURNING id, user_id, title, expires_at
+	`
+	return s.sweep(query, before)
+}
+
+func (s *PostgresAnnouncementsStore) sweep(query string, args ...any) ([]model.Announcement, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var announcements []model.Announcement
+	for rows.Next() {
+		var an model.Announcement
+		if err := rows.Scan(&an.Id, &an.UserId, &an.Article, &an.ExpiresAt); err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, an)
+	}
+
+	return announcements, rows.Err()
+}
+
+// DeleteAnnouncement deletes the announcement if it still has the version.
+func (s *PostgresAnnouncementsStore) DeleteAnnouncement(id, version int64) error {
+	res, err := s.DB.Exec(`DELETE FROM announcements WHERE id = $1 AND version = $2`, id, version)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := s.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM announcements WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return ErrAnnouncementNotFound
+}
+
+func (s *PostgresAnnouncementsStore) SubscribeToPriceDrops(userId, announcementId int64) error {
+	query := `INSERT INTO price_alerts(user_id, announcement_id) VALUES($1, $2) ON CONFLICT DO NOTHING`
+	_, err := s.DB.Exec(query, userId, announcementId)
+	return err
+}
+
+func (s *PostgresAnnouncementsStore) UnsubscribeFromPriceDrops(userId, announcementId int64) error {
+	query := `DELETE FROM price_alerts WHERE user_id = $1 AND announcement_id = $2`
+	_, err := s.DB.Exec(query, userId, announcementId)
+	return err
+}
+
+func (s *PostgresAnnouncementsStore) GetPriceDropSubscribers(announcementId int64) ([]int64, error) {
+	rows, err := s.DB.Query(`SELECT user_id FROM price_alerts WHERE announcement_id = $1`, announcementId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []int64
+	for rows.Next() {
+		var userId int64
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, userId)
+	}
+
+	return subscribers, rows.Err()
+}
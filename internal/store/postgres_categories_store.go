@@ -0,0 +1,66 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"marketplace-service/internal/model"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+type PostgresCategoriesStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresCategoriesStore(db *sql.DB) *PostgresCategoriesStore {
+	return &PostgresCategoriesStore{DB: db}
+}
+
+func scanCategory(row interface{ Scan(...any) error }, c *model.Category) error {
+	var attributes []byte
+
+	if err := row.Scan(&c.Id, &c.Slug, &c.Name, &attributes); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(attributes, &c.Attributes)
+}
+
+func (s *PostgresCategoriesStore) GetCategories() ([]model.Category, error) {
+	rows, err := s.DB.Query(`SELECT id, slug, name, attributes FROM categories ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []model.Category{}
+	for rows.Next() {
+		var c model.Category
+		if err := scanCategory(rows, &c); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+func (s *PostgresCategoriesStore) getCategory(query string, arg any) (*model.Category, error) {
+	c := &model.Category{}
+	if err := scanCategory(s.DB.QueryRow(query, arg), c); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *PostgresCategoriesStore) GetCategoryBySlug(slug string) (*model.Category, error) {
+	return s.getCategory(`SELECT id, slug, name, attributes FROM categories WHERE slug = $1`, slug)
+}
+
+func (s *PostgresCategoriesStore) GetCategoryById(id int64) (*model.Category, error) {
+	return s.getCategory(`SELECT id, slug, name, attributes FROM categories WHERE id = $1`, id)
+}
@@ -0,0 +1,303 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"marketplace-service/internal/model"
+)
+
+var ErrConversationNotFound = errors.New("conversation not found")
+var ErrOwnAnnouncement = errors.New("announcement belongs to the user")
+var ErrUsersBlocked = errors.New("messaging between the users is blocked")
+
+type PostgresConversationsStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresConversationsStore(db *sql.DB) *PostgresConversationsStore {
+	return &PostgresConversationsStore{DB: db}
+}
+
+// conversationColumns selects a conversation together with the number of
+// messages unread by the user passed as $1.
+const conversationColumns = `
+	SELECT c.id, c.announcement_id, a.title,
+	c.buyer_id, buyers.username, c.seller_id, sellers.username,
+	(SELECT COUNT(*) FROM messages m
+		WHERE m.conversation_id = c.id AND m.sender_id <> $1 AND m.read_at IS NULL),
+	c.created_at, c.last_message_at
+	FROM conversations c
+	JOIN announcements a ON a.id = c.announcement_id
+	JOIN users buyers ON buyers.id = c.buyer_id
+	JOIN users sellers ON sellers.id = c.seller_id
+`
+
+func scanConversation(row interface{ Scan(...any) error }, c *model.Conversation) error {
+	return row.Scan(
+		&c.Id,
+		&c.AnnouncementId,
+		&c.AnnouncementTitle,
+		&c.BuyerId,
+		&c.BuyerUsername,
+		&c.SellerId,
+		&c.SellerUsername,
+		&c.UnreadCount,
+		&c.Date,
+		&c.LastMessageAt,
+	)
+}
+
+func extractConversations(rows *sql.Rows) ([]model.Conversation, error) {
+	defer rows.Close()
+
+	conversations := []model.Conversation{}
+	for rows.Next() {
+		var c model.Conversation
+		if err := scanConversation(rows, &c); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+func (s *PostgresConversationsStore) GetOrCreateConversation(announcementId, buyerId int64) (*model.Conversation, error) {
+	var sellerId int64
+	err := s.DB.QueryRow(`SELECT user_id FROM announcements WHERE id = $1 AND status <> 'draft'`, announcementId).Scan(&sellerId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, err
+	}
+
+	if sellerId == buyerId {
+		return nil, ErrOwnAnnouncement
+	}
+
+	// A blocked buyer must not leave an empty conversation behind, so the
+	// block is checked by the insert itself: a block made in between can not
+	// slip through.
+	query := `
+		INSERT INTO conversations(announcement_id, buyer_id, seller_id)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $2 AND blocked_id = $3) OR (blocker_id = $3 AND blocked_id = $2)
+		)
+		ON CONFLICT (announcement_id, buyer_id) DO UPDATE SET announcement_id = EXCLUDED.announcement_id
+		RETURNING id
+	`
+	var id int64
+	if err := s.DB.QueryRow(query, announcementId, buyerId, sellerId).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUsersBlocked
+		}
+		return nil, err
+	}
+
+	return s.GetConversationById(id, buyerId)
+}
+
+func (s *PostgresConversationsStore) GetConversationById(id, currentUserId int64) (*model.Conversation, error) {
+	query := conversationColumns + `WHERE c.id = $2`
+
+	c := &model.Conversation{}
+	if err := scanConversation(s.DB.QueryRow(query, currentUserId, id), c); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *PostgresConversationsStore) GetConversationsByPage(userId int64, page, limit int) ([]model.Conversation, error) {
+	query := conversationColumns + `
+		WHERE c.buyer_id = $1 OR c.seller_id = $1
+		ORDER BY c.last_message_at DESC
+		LIMIT $2
+		OFFSET $3
+	`
+	offset := (page - 1) * limit
+
+	rows, err := s.DB.Query(query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractConversations(rows)
+}
+
+// GetSellerInbox pages the announcements with conversations, every listing
+// comes with all of its conversations.
+func (s *PostgresConversationsStore) GetSellerInbox(sellerId int64, page, limit int) ([]model.ListingInbox, error) {
+	query := conversationColumns + `
+		WHERE c.seller_id = $1 AND c.announcement_id IN (
+			SELECT DISTINCT announcement_id FROM conversations
+			WHERE seller_id = $1
+			ORDER BY announcement_id DESC
+			LIMIT $2
+			OFFSET $3
+		)
+		ORDER BY c.announcement_id DESC, c.last_message_at DESC
+	`
+	offset := (page - 1) * limit
+
+	rows, err := s.DB.Query(query, sellerId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations, err := extractConversations(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	inbox := []model.ListingInbox{}
+	for _, c := range conversations {
+		if len(inbox) == 0 || inbox[len(inbox)-1].AnnouncementId != c.AnnouncementId {
+			inbox = append(inbox, model.ListingInbox{
+				AnnouncementId:    c.AnnouncementId,
+				AnnouncementTitle: c.AnnouncementTitle,
+			})
+		}
+
+		listing := &inbox[len(inbox)-1]
+		listing.UnreadCount += c.UnreadCount
+		listing.Conversations = append(listing.Conversations, c)
+	}
+
+	return inbox, nil
+}
+
+func (s *PostgresConversationsStore) CountUnreadMessages(userId int64) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE (c.buyer_id = $1 OR c.seller_id = $1) AND m.sender_id <> $1 AND m.read_at IS NULL
+	`
+	var count int
+	err := s.DB.QueryRow(query, userId).Scan(&count)
+	return count, err
+}
+
+func (s *PostgresConversationsStore) CreateMessage(m *model.Message) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO messages(conversation_id, sender_id, text)
+		SELECT c.id, $2, $3 FROM conversations c
+		WHERE c.id = $1 AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = c.buyer_id AND b.blocked_id = c.seller_id)
+			OR (b.blocker_id = c.seller_id AND b.blocked_id = c.buyer_id)
+		)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(query, m.ConversationId, m.SenderId, m.Text).Scan(&m.Id, &m.Date); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUsersBlocked
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE conversations SET last_message_at = $1 WHERE id = $2`, m.Date, m.ConversationId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func extractMessages(rows *sql.Rows) ([]model.Message, error) {
+	defer rows.Close()
+
+	messages := []model.Message{}
+	for rows.Next() {
+		var m model.Message
+		if err := rows.Scan(&m.Id, &m.ConversationId, &m.SenderId, &m.Text, &m.IsRead, &m.Date); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *PostgresConversationsStore) GetMessagesByPage(conversationId int64, page, limit int) ([]model.Message, error) {
+	query := `
+		SELECT id, conversation_id, sender_id, text, read_at IS NOT NULL, created_at
+		FROM messages
+		WHERE conversation_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+		OFFSET $3
+	`
+	offset := (page - 1) * limit
+
+	rows, err := s.DB.Query(query, conversationId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractMessages(rows)
+}
+
+func (s *PostgresConversationsStore) GetMessagesAfter(userId, lastId int64, limit int) ([]model.Message, error) {
+	query := `
+		SELECT m.id, m.conversation_id, m.sender_id, m.text, m.read_at IS NOT NULL, m.created_at
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE (c.buyer_id = $1 OR c.seller_id = $1) AND m.id > $2
+		ORDER BY m.id ASC
+		LIMIT $3
+	`
+
+	rows, err := s.DB.Query(query, userId, lastId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractMessages(rows)
+}
+
+// MarkConversationRead marks the messages received by the reader as read and
+// returns the id of the newest of them, or 0 if there was nothing to mark.
+func (s *PostgresConversationsStore) MarkConversationRead(conversationId, readerId int64) (int64, error) {
+	query := `
+		WITH updated AS (
+			UPDATE messages SET read_at = CURRENT_TIMESTAMP
+			WHERE conversation_id = $1 AND sender_id <> $2 AND read_at IS NULL
+			RETURNING id
+		)
+		SELECT COALESCE(MAX(id), 0) FROM updated
+	`
+	var lastReadId int64
+	err := s.DB.QueryRow(query, conversationId, readerId).Scan(&lastReadId)
+	return lastReadId, err
+}
+
+func (s *PostgresConversationsStore) BlockUser(blockerId, blockedId int64) error {
+	query := `INSERT INTO user_blocks(blocker_id, blocked_id) VALUES($1, $2) ON CONFLICT DO NOTHING`
+	_, err := s.DB.Exec(query, blockerId, blockedId)
+	return err
+}
+
+func (s *PostgresConversationsStore) UnblockUser(blockerId, blockedId int64) error {
+	query := `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`
+	_, err := s.DB.Exec(query, blockerId, blockedId)
+	return err
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"marketplace-service/internal/logger"
+)
+
+const feedChannel = "announcements_changed"
+
+// NotifyFeedChanged tells the services that cache the public list that
+// announcements were changed by another process, like the admin CLI.
+func NotifyFeedChanged(db *sql.DB) error {
+	_, err := db.Exec(`SELECT pg_notify($1, '')`, feedChannel)
+	return err
+}
+
+// ListenFeedChanges invalidates the cache on every NotifyFeedChanged until
+// the returned listener is closed. The cache is also cleared after the
+// connection is re-established, since the notifications sent meanwhile are
+// lost.
+func ListenFeedChanges(connInfo string, feed FeedInvalidator, l logger.Logger) (*pq.Listener, error) {
+	listener := pq.NewListener(connInfo, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			l.Error("Feed listener: ", err)
+		}
+	})
+
+	if err := listener.Listen(feedChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		for range listener.Notify {
+			feed.Invalidate()
+		}
+	}()
+
+	return listener, nil
+}
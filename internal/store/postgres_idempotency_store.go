@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"marketplace-service/internal/model"
+	"time"
+)
+
+type PostgresIdempotencyStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresIdempotencyStore(db *sql.DB) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{DB: db}
+}
+
+func (s *PostgresIdempotencyStore) Reserve(userId int64, key, fingerprint string, ttl, lockTimeout time.Duration) (bool, *model.IdempotentRequest, error) {
+	query := `
+		INSERT INTO idempotency_keys(user_id, key, fingerprint) VALUES($1, $2, $3)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+		fingerprint = EXCLUDED.fingerprint, completed = false, status_code = NULL, headers = NULL, body = NULL,
+		created_at = CURRENT_TIMESTAMP
+		WHERE idempotency_keys.created_at < CURRENT_TIMESTAMP - make_interval(secs => $4)
+		OR (NOT idempotency_keys.completed AND idempotency_keys.created_at < CURRENT_TIMESTAMP - make_interval(secs => $5))
+		RETURNING true
+	`
+	var reserved bool
+	err := s.DB.QueryRow(query, userId, key, fingerprint, ttl.Seconds(), lockTimeout.Seconds()).Scan(&reserved)
+	if err == nil {
+		return true, nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, nil, err
+	}
+
+	var request model.IdempotentRequest
+	var status sql.NullInt64
+	var headers []byte
+
+	query = `SELECT fingerprint, completed, status_code, headers, body FROM idempotency_keys WHERE user_id = $1 AND key = $2`
+	err = s.DB.QueryRow(query, userId, key).Scan(&request.Fingerprint, &request.Completed, &status, &headers, &request.Body)
+	if err != nil {
+		return false, nil, err
+	}
+
+	request.Status = int(status.Int64)
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &request.Header); err != nil {
+			return false, nil, err
+		}
+	}
+
+	return false, &request, nil
+}
+
+func (s *PostgresIdempotencyStore) Complete(userId int64, key string, request *model.IdempotentRequest) error {
+	headers, err := json.Marshal(request.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE idempotency_keys SET completed = true, status_code = $3, headers = $4, body = $5
+		WHERE user_id = $1 AND key = $2
+	`
+	_, err = s.DB.Exec(query, userId, key, request.Status, headers, request.Body)
+	return err
+}
+
+func (s *PostgresIdempotencyStore) Release(userId int64, key string) error {
+	_, err := s.DB.Exec(`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND NOT completed`, userId, key)
+	return err
+}
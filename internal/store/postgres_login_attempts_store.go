@@ -0,0 +1,52 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresLoginAttemptsStore shares the counters between all instances of the
+// service.
+type PostgresLoginAttemptsStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresLoginAttemptsStore(db *sql.DB) *PostgresLoginAttemptsStore {
+	return &PostgresLoginAttemptsStore{DB: db}
+}
+
+func (s *PostgresLoginAttemptsStore) RegisterFailure(key string, window time.Duration) (int, error) {
+	query := `
+		INSERT INTO login_attempts(key, failures, last_failure_at) VALUES($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET
+		failures = CASE WHEN GREATEST(login_attempts.last_failure_at, login_attempts.locked_until) < CURRENT_TIMESTAMP - make_interval(secs => $2)
+			THEN 1 ELSE login_attempts.failures + 1 END,
+		last_failure_at = CURRENT_TIMESTAMP
+		RETURNING failures
+	`
+	var failures int
+	err := s.DB.QueryRow(query, key, window.Seconds()).Scan(&failures)
+	return failures, err
+}
+
+func (s *PostgresLoginAttemptsStore) LockUntil(key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = GREATEST(COALESCE(locked_until, $2), $2) WHERE key = $1`
+	_, err := s.DB.Exec(query, key, until)
+	return err
+}
+
+func (s *PostgresLoginAttemptsStore) LockedUntil(keys ...string) (time.Time, error) {
+	var until sql.NullTime
+	err := s.DB.QueryRow(`SELECT MAX(locked_until) FROM login_attempts WHERE key = ANY($1)`, pq.Array(keys)).Scan(&until)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return until.Time, nil
+}
+
+func (s *PostgresLoginAttemptsStore) Reset(key string) error {
+	_, err := s.DB.Exec(`DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
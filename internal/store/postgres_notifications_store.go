@@ -0,0 +1,105 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"marketplace-service/internal/model"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type PostgresNotificationsStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresNotificationsStore(db *sql.DB) *PostgresNotificationsStore {
+	return &PostgresNotificationsStore{DB: db}
+}
+
+func (s *PostgresNotificationsStore) CreateNotification(n *model.Notification) error {
+	query := `INSERT INTO notifications(user_id, type, payload) VALUES($1, $2, $3) RETURNING id, created_at`
+	return s.DB.QueryRow(query, n.UserId, n.Type, []byte(n.Payload)).Scan(&n.Id, &n.Date)
+}
+
+func extractNotifications(rows *sql.Rows) ([]model.Notification, error) {
+	defer rows.Close()
+
+	notifications := []model.Notification{}
+	for rows.Next() {
+		var n model.Notification
+		var payload []byte
+
+		if err := rows.Scan(&n.Id, &n.UserId, &n.Type, &payload, &n.IsRead, &n.Date); err != nil {
+			return nil, err
+		}
+		n.Payload = payload
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (s *PostgresNotificationsStore) GetNotificationsByPage(userId, page, limit int, filter string) ([]model.Notification, error) {
+	query := `
+		SELECT id, user_id, type, payload, read_at IS NOT NULL, created_at
+		FROM notifications
+		WHERE user_id = $1
+		AND ($4 = 'all' OR ($4 = 'read') = (read_at IS NOT NULL))
+		ORDER BY id DESC
+		LIMIT $2
+		OFFSET $3
+	`
+	offset := (page - 1) * limit
+
+	rows, err := s.DB.Query(query, userId, limit, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractNotifications(rows)
+}
+
+func (s *PostgresNotificationsStore) GetNotificationsAfter(userId int, lastId int64, limit int) ([]model.Notification, error) {
+	query := `
+		SELECT id, user_id, type, payload, read_at IS NOT NULL, created_at
+		FROM notifications
+		WHERE user_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
+	`
+
+	rows, err := s.DB.Query(query, userId, lastId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractNotifications(rows)
+}
+
+func (s *PostgresNotificationsStore) MarkNotificationRead(userId int, id int64) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2`
+	res, err := s.DB.Exec(query, id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *PostgresNotificationsStore) MarkAllNotificationsRead(userId int) error {
+	query := `UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL`
+	_, err := s.DB.Exec(query, userId)
+	return err
+}
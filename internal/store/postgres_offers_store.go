@@ -0,0 +1,225 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"marketplace-service/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrOfferNotFound = errors.New("offer not found")
+var ErrOfferAlreadyOpen = errors.New("buyer already has an open offer")
+var ErrOfferNotOpen = errors.New("offer is no longer open")
+var ErrAnnouncementNotActive = errors.New("announcement is not active")
+
+type PostgresOffersStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresOffersStore(db *sql.DB) *PostgresOffersStore {
+	return &PostgresOffersStore{DB: db}
+}
+
+// Open offers whose time is over are reported as expired without waiting for
+// anyone to update them.
+const offerColumns = `
+	SELECT o.id, o.announcement_id, o.buyer_id, users.username, o.amount, o.counter_amount,
+	CASE WHEN o.status IN ('pending', 'countered') AND o.expires_at <= CURRENT_TIMESTAMP
+		THEN 'expired' ELSE o.status END,
+	o.expires_at, o.created_at, o.updated_at
+	FROM offers o
+	JOIN users ON users.id = o.buyer_id
+`
+
+func scanOffer(row interface{ Scan(...any) error }, o *model.Offer) error {
+	return row.Scan(
+		&o.Id,
+		&o.AnnouncementId,
+		&o.BuyerId,
+		&o.BuyerUsername,
+		&o.Amount,
+		&o.CounterAmount,
+		&o.Status,
+		&o.ExpiresAt,
+		&o.Date,
+		&o.UpdatedAt,
+	)
+}
+
+func extractOffers(rows *sql.Rows) ([]model.Offer, error) {
+	defer rows.Close()
+
+	offers := []model.Offer{}
+	for rows.Next() {
+		var o model.Offer
+		if err := scanOffer(rows, &o); err != nil {
+			return nil, err
+		}
+		offers = append(offers, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return offers, nil
+}
+
+func (s *PostgresOffersStore) CreateOffer(o *model.Offer) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Free the open offer slot taken by an offer that has expired.
+	expire := `
+		UPDATE offers SET status = 'expired', updated_at = CURRENT_TIMESTAMP
+		WHERE announcement_id = $1 AND buyer_id = $2
+		AND status IN ('pending', 'countered') AND expires_at <= CURRENT_TIMESTAMP
+	`
+	if _, err := tx.Exec(expire, o.AnnouncementId, o.BuyerId); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO offers(announcement_id, buyer_id, amount, expires_at) VALUES($1, $2, $3, $4)
+		RETURNING id, status, created_at, updated_at
+	`
+	err = tx.QueryRow(query, o.AnnouncementId, o.BuyerId, o.Amount, o.ExpiresAt).Scan(&o.Id, &o.Status, &o.Date, &o.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrOfferAlreadyOpen
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresOffersStore) GetOfferById(id int64) (*model.Offer, error) {
+	o := &model.Offer{}
+	if err := scanOffer(s.DB.QueryRow(offerColumns+`WHERE o.id = $1`, id), o); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOfferNotFound
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// GetOffersByAnnouncement returns the offers of the buyer, or all of them if buyerId is 0.
+func (s *PostgresOffersStore) GetOffersByAnnouncement(announcementId, buyerId int64) ([]model.Offer, error) {
+	query := offerColumns + `
+		WHERE o.announcement_id = $1 AND ($2 = 0 OR o.buyer_id = $2)
+		ORDER BY o.created_at DESC
+	`
+
+	rows, err := s.DB.Query(query, announcementId, buyerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractOffers(rows)
+}
+
+func (s *PostgresOffersStore) CounterOffer(id int64, amount int32, expiresAt time.Time) error {
+	query := `
+		UPDATE offers SET status = 'countered', counter_amount = $2, expires_at = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending' AND expires_at > CURRENT_TIMESTAMP
+	`
+	return execOfferTransition(s.DB, query, id, amount, expiresAt)
+}
+
+func (s *PostgresOffersStore) DeclineOffer(id int64, expectedStatus string) error {
+	query := `
+		UPDATE offers SET status = 'declined', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $2 AND expires_at > CURRENT_TIMESTAMP
+	`
+	return execOfferTransition(s.DB, query, id, expectedStatus)
+}
+
+func execOfferTransition(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, query string, args ...any) error {
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrOfferNotOpen
+	}
+	return nil
+}
+
+// AcceptOffer accepts the open offer, reserves its announcement and declines
+// the other open offers on it. Only ids and buyers of the declined offers are returned.
+func (s *PostgresOffersStore) AcceptOffer(id int64, expectedStatus string) ([]model.Offer, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var announcementId int64
+	accept := `
+		UPDATE offers SET status = 'accepted',
+		amount = CASE WHEN status = 'countered' THEN counter_amount ELSE amount END,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $2 AND expires_at > CURRENT_TIMESTAMP
+		RETURNING announcement_id
+	`
+	if err := tx.QueryRow(accept, id, expectedStatus).Scan(&announcementId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOfferNotOpen
+		}
+		return nil, err
+	}
+
+	reserve := `UPDATE announcements SET status = 'reserved', version = version + 1 WHERE id = $1 AND status = 'active'`
+	res, err := tx.Exec(reserve, announcementId)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, ErrAnnouncementNotActive
+	}
+
+	decline := `
+		UPDATE offers SET status = 'declined', updated_at = CURRENT_TIMESTAMP
+		WHERE announcement_id = $1 AND id <> $2 AND status IN ('pending', 'countered')
+		RETURNING id, buyer_id
+	`
+	rows, err := tx.Query(decline, announcementId, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var declined []model.Offer
+	for rows.Next() {
+		o := model.Offer{AnnouncementId: announcementId, Status: model.OfferDeclined}
+		if err := rows.Scan(&o.Id, &o.BuyerId); err != nil {
+			return nil, err
+		}
+		declined = append(declined, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return declined, nil
+}
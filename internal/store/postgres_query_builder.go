@@ -0,0 +1,166 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sortClauses holds the SQL of every sort order. Only these fragments ever get
+// into ORDER BY.
+var sortClauses = map[SortOrder]string{
+	SortPriceAsc:  "price ASC",
+	SortPriceDesc: "price DESC",
+	SortDateAsc:   "announcements.created_at ASC",
+	SortDateDesc:  "announcements.created_at DESC",
+	SortPriceDrop: "COALESCE(last_change.old_price - price, 0) DESC",
+	SortDistance:  "distance_km ASC NULLS LAST",
+}
+
+// queryBuilder composes the announcement list query. The text of the query is
+// made only of constant fragments and placeholders; every value, including the
+// names of attributes, is passed as an argument.
+type queryBuilder struct {
+	args     []any
+	distance string
+	where    []string
+	orderBy  []string
+}
+
+func newQueryBuilder(currentUserId int) *queryBuilder {
+	b := &queryBuilder{distance: noDistance}
+	b.arg(currentUserId)
+	return b
+}
+
+// arg adds the value to the arguments and returns its placeholder.
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *queryBuilder) and(condition string) {
+	b.where = append(b.where, condition)
+}
+
+func (b *queryBuilder) filter(f AnnouncementFilter) error {
+	b.and("price >= " + b.arg(f.MinPrice))
+	b.and("price <= " + b.arg(f.MaxPrice))
+
+	if f.Status == "all" {
+		b.and("status IN ('active', 'reserved')")
+	} else {
+		b.and("status = " + b.arg(f.Status))
+	}
+	b.and("(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)")
+
+	if f.City != "" {
+		b.and("lower(city) = lower(" + b.arg(f.City) + ")")
+	}
+
+	if f.Near != nil {
+		b.near(*f.Near)
+	}
+
+	if f.Category != nil {
+		b.and("announcements.category_id = " + b.arg(f.Category.Id))
+		for _, attribute := range f.Category.Attributes {
+			if err := b.attribute(attribute); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *queryBuilder) near(n Nearby) {
+	point := "ll_to_earth(" + b.arg(n.Lat) + ", " + b.arg(n.Lon) + ")"
+	distance := "earth_distance(" + point + ", ll_to_earth(latitude, longitude))"
+	b.distance = "ROUND((" + distance + " / 1000)::numeric, 2)::float8"
+
+	if n.RadiusKm > 0 {
+		radius := b.arg(n.RadiusKm * 1000)
+		b.and("latitude IS NOT NULL AND longitude IS NOT NULL")
+		b.and("earth_box(" + point + ", " + radius + ") @> ll_to_earth(latitude, longitude)")
+		b.and(distance + " <= " + radius)
+	}
+}
+
+func (b *queryBuilder) attribute(f AttributeFilter) error {
+	number := func(path string) string {
+		return "(CASE WHEN jsonb_typeof(" + path + ") = 'number' THEN (" + path + ")::text::numeric END)"
+	}
+
+	switch f.Op {
+	case AttributeEquals:
+		contains, err := json.Marshal(map[string]any{f.Name: f.Value})
+		if err != nil {
+			return err
+		}
+		b.and("announcements.attributes @> " + b.arg(contains) + "::jsonb")
+
+	case AttributeMin:
+		b.and(number("announcements.attributes -> "+b.arg(f.Name)+"::text") + " >= " + b.arg(f.Value))
+
+	case AttributeMax:
+		b.and(number("announcements.attributes -> "+b.arg(f.Name)+"::text") + " <= " + b.arg(f.Value))
+
+	case AttributeContains:
+		name, value := b.arg(f.Name), b.arg(f.Value)
+		b.and(number("announcements.attributes -> "+name+"::text -> 'from'") + " <= " + value)
+		b.and(number("announcements.attributes -> "+name+"::text -> 'to'") + " >= " + value)
+
+	default:
+		return fmt.Errorf("unsupported attribute filter %q", f.Op)
+	}
+
+	return nil
+}
+
+func (b *queryBuilder) sort(orders []SortOrder) error {
+	for _, order := range orders {
+		clause, ok := sortClauses[order]
+		if !ok {
+			return fmt.Errorf("unknown sort order %q", order)
+		}
+		b.orderBy = append(b.orderBy, clause)
+	}
+
+	// The id makes the order total, so that pages do not overlap.
+	b.orderBy = append(b.orderBy, "announcements.id DESC")
+	return nil
+}
+
+// build returns the query of the page and its arguments.
+func (b *queryBuilder) build(page, limit int) (string, []any) {
+	var query strings.Builder
+
+	query.WriteString(selectAnnouncements(b.distance))
+	if len(b.where) > 0 {
+		query.WriteString(" WHERE " + strings.Join(b.where, " AND "))
+	}
+	if len(b.orderBy) > 0 {
+		query.WriteString(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+	query.WriteString(" LIMIT " + b.arg(limit))
+	query.WriteString(" OFFSET " + b.arg((page-1)*limit))
+
+	return query.String(), b.args
+}
+
+// buildAnnouncementQuery turns the typed query into SQL and its arguments.
+func buildAnnouncementQuery(q AnnouncementQuery) (string, []any, error) {
+	b := newQueryBuilder(q.CurrentUserId)
+
+	if err := b.filter(q.Filter); err != nil {
+		return "", nil, err
+	}
+	if err := b.sort(q.Sort); err != nil {
+		return "", nil, err
+	}
+
+	query, args := b.build(q.Page, q.Limit)
+	return query, args, nil
+}
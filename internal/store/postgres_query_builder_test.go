@@ -0,0 +1,194 @@
+package store
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// hostile pieces of the generated input. Each input also gets random letters,
+// so that it can not match a constant fragment of the query by chance.
+var injections = []string{"'", "';", "--", "/*", "*/", ")", "\"", "$1", " OR 1=1", "DROP TABLE users", "\\", "\x00", "ё"}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+func randomInput(rnd *rand.Rand) string {
+	var b strings.Builder
+	b.WriteString(injections[rnd.Intn(len(injections))])
+	for i := 0; i < 6+rnd.Intn(10); i++ {
+		if rnd.Intn(4) == 0 {
+			b.WriteString(injections[rnd.Intn(len(injections))])
+		} else {
+			b.WriteByte(byte('a' + rnd.Intn(26)))
+		}
+	}
+	return b.String()
+}
+
+// randomQuery is an AnnouncementQuery together with the strings and values
+// that came from the user.
+type randomQuery struct {
+	query  AnnouncementQuery
+	inputs []string
+	values []any
+	valid  bool
+}
+
+func (randomQuery) Generate(rnd *rand.Rand, size int) reflect.Value {
+	rq := randomQuery{valid: true}
+	q := &rq.query
+
+	q.CurrentUserId = rnd.Intn(1000)
+	q.Page = 1 + rnd.Intn(100)
+	q.Limit = 1 + rnd.Intn(100)
+	q.Filter.MinPrice = rnd.Intn(1000)
+	q.Filter.MaxPrice = q.Filter.MinPrice + rnd.Intn(1_000_000)
+	rq.values = append(rq.values, q.CurrentUserId, q.Filter.MinPrice, q.Filter.MaxPrice, q.Limit, (q.Page-1)*q.Limit)
+
+	switch rnd.Intn(3) {
+	case 0:
+		q.Filter.Status = "all"
+	case 1:
+		q.Filter.Status = "active"
+		rq.values = append(rq.values, q.Filter.Status)
+	default:
+		q.Filter.Status = randomInput(rnd)
+		rq.inputs = append(rq.inputs, q.Filter.Status)
+		rq.values = append(rq.values, q.Filter.Status)
+	}
+
+	if rnd.Intn(2) == 0 {
+		q.Filter.City = randomInput(rnd)
+		rq.inputs = append(rq.inputs, q.Filter.City)
+		rq.values = append(rq.values, q.Filter.City)
+	}
+
+	if rnd.Intn(2) == 0 {
+		near := &Nearby{Lat: rnd.Float64()*180 - 90, Lon: rnd.Float64()*360 - 180}
+		if rnd.Intn(2) == 0 {
+			near.RadiusKm = rnd.Float64() * 100
+			rq.values = append(rq.values, near.RadiusKm*1000)
+		}
+		q.Filter.Near = near
+		rq.values = append(rq.values, near.Lat, near.Lon)
+	}
+
+	if rnd.Intn(2) == 0 {
+		category := &CategoryFilter{Id: rnd.Int63n(1000)}
+		rq.values = append(rq.values, category.Id)
+
+		ops := []string{AttributeEquals, AttributeMin, AttributeMax, AttributeContains}
+		for i := 0; i < rnd.Intn(size%5+1); i++ {
+			attribute := AttributeFilter{Name: randomInput(rnd), Op: ops[rnd.Intn(len(ops))]}
+			if rnd.Intn(20) == 0 {
+				attribute.Op = randomInput(rnd)
+				rq.valid = false
+			}
+			if rnd.Intn(2) == 0 {
+				value := randomInput(rnd)
+				attribute.Value = value
+				rq.inputs = append(rq.inputs, value)
+			} else {
+				attribute.Value = rnd.Float64() * 1000
+			}
+			rq.inputs = append(rq.inputs, attribute.Name)
+			rq.values = append(rq.values, attribute.Name, attribute.Value)
+			category.Attributes = append(category.Attributes, attribute)
+		}
+		q.Filter.Category = category
+	}
+
+	orders := []SortOrder{SortPriceAsc, SortPriceDesc, SortDateAsc, SortDateDesc, SortPriceDrop, SortDistance}
+	for i := 0; i < rnd.Intn(4); i++ {
+		if rnd.Intn(20) == 0 {
+			order := randomInput(rnd)
+			q.Sort = append(q.Sort, SortOrder(order))
+			rq.inputs = append(rq.inputs, order)
+			rq.valid = false
+			continue
+		}
+		q.Sort = append(q.Sort, orders[rnd.Intn(len(orders))])
+	}
+
+	return reflect.ValueOf(rq)
+}
+
+// inArgs reports whether the value is one of the arguments, either by itself
+// or as a name or a value of the JSON the equality filters are passed in.
+func inArgs(args []any, value any) bool {
+	for _, arg := range args {
+		if reflect.DeepEqual(arg, value) {
+			return true
+		}
+
+		data, ok := arg.([]byte)
+		if !ok {
+			continue
+		}
+		var contains map[string]any
+		if err := json.Unmarshal(data, &contains); err != nil {
+			continue
+		}
+		for name, v := range contains {
+			if reflect.DeepEqual(name, value) || reflect.DeepEqual(v, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestBuildAnnouncementQueryKeepsInputOutOfSQL(t *testing.T) {
+	property := func(rq randomQuery) bool {
+		query, args, err := buildAnnouncementQuery(rq.query)
+		if !rq.valid {
+			if err == nil {
+				t.Logf("no error for an unknown sort order or attribute filter: %+v", rq.query)
+				return false
+			}
+			return query == "" && args == nil
+		}
+		if err != nil {
+			t.Logf("unexpected error %v for %+v", err, rq.query)
+			return false
+		}
+
+		for _, input := range rq.inputs {
+			if strings.Contains(query, input) {
+				t.Logf("input %q reached the SQL: %s", input, query)
+				return false
+			}
+		}
+
+		for _, value := range rq.values {
+			if !inArgs(args, value) {
+				t.Logf("value %#v is missing from the arguments %#v", value, args)
+				return false
+			}
+		}
+
+		var numbers []int
+		for _, match := range placeholder.FindAllStringSubmatch(query, -1) {
+			n, _ := strconv.Atoi(match[1])
+			numbers = append(numbers, n)
+		}
+		slices.Sort(numbers)
+		numbers = slices.Compact(numbers)
+		if len(numbers) != len(args) || (len(numbers) > 0 && (numbers[0] != 1 || numbers[len(numbers)-1] != len(args))) {
+			t.Logf("placeholders %v do not match %d arguments: %s", numbers, len(args), query)
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PostgresRateLimitStore shares the buckets between all instances of the
+// service. A missing bucket is a full one, so Cleanup deletes the buckets
+// that have refilled.
+type PostgresRateLimitStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresRateLimitStore(db *sql.DB) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{DB: db}
+}
+
+func (s *PostgresRateLimitStore) Take(key string, capacity int, period time.Duration) (bool, float64, error) {
+	// refilled is the number of tokens in the bucket before taking one.
+	const refilled = `LEAST($2::float8, rate_limit_buckets.tokens +
+		EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_buckets.updated_at) * $2::float8 / $3::float8)`
+
+	query := `
+		INSERT INTO rate_limit_buckets(key, tokens, allowed, updated_at, expires_at)
+		VALUES($1, $2::float8 - 1, true, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP + make_interval(secs => $3::float8))
+		ON CONFLICT (key) DO UPDATE SET
+		tokens = CASE WHEN ` + refilled + ` >= 1 THEN ` + refilled + ` - 1 ELSE ` + refilled + ` END,
+		allowed = ` + refilled + ` >= 1,
+		updated_at = CURRENT_TIMESTAMP,
+		expires_at = EXCLUDED.expires_at
+		RETURNING allowed, tokens
+	`
+
+	var allowed bool
+	var tokens float64
+	err := s.DB.QueryRow(query, key, capacity, period.Seconds()).Scan(&allowed, &tokens)
+	return allowed, tokens, err
+}
+
+func (s *PostgresRateLimitStore) Cleanup() error {
+	_, err := s.DB.Exec(`DELETE FROM rate_limit_buckets WHERE expires_at < CURRENT_TIMESTAMP`)
+	return err
+}
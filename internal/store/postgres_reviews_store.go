@@ -0,0 +1,159 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"marketplace-service/internal/model"
+
+	"github.com/lib/pq"
+)
+
+var ErrReviewAlreadyExists = errors.New("review already exists")
+var ErrReviewNotFound = errors.New("review not found")
+var ErrReviewAlreadyReplied = errors.New("review already has a reply")
+
+type PostgresReviewsStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresReviewsStore(db *sql.DB) *PostgresReviewsStore {
+	return &PostgresReviewsStore{DB: db}
+}
+
+const reviewColumns = `
+	SELECT r.id, r.seller_id, sellers.username, r.buyer_id, buyers.username,
+	r.rating, r.text, r.reply, r.replied_at, r.created_at, r.updated_at
+	FROM reviews r
+	JOIN users sellers ON sellers.id = r.seller_id
+	JOIN users buyers ON buyers.id = r.buyer_id
+`
+
+func scanReview(row interface{ Scan(...any) error }, r *model.Review) error {
+	var reply sql.NullString
+	var repliedAt sql.NullTime
+
+	err := row.Scan(
+		&r.Id,
+		&r.SellerId,
+		&r.SellerUsername,
+		&r.BuyerId,
+		&r.BuyerUsername,
+		&r.Rating,
+		&r.Text,
+		&reply,
+		&repliedAt,
+		&r.Date,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if reply.Valid {
+		r.Reply = &reply.String
+	}
+	if repliedAt.Valid {
+		r.RepliedAt = &repliedAt.Time
+	}
+	return nil
+}
+
+// HasConversation reports whether the buyer wrote to the seller. A
+// conversation without messages from the buyer does not count, and an answer
+// of the seller is not required, or a seller could avoid reviews by keeping
+// silent.
+func (s *PostgresReviewsStore) HasConversation(buyerId, sellerId int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM conversations c
+			JOIN messages m ON m.conversation_id = c.id AND m.sender_id = c.buyer_id
+			WHERE c.buyer_id = $1 AND c.seller_id = $2
+		)
+	`
+	var exists bool
+	err := s.DB.QueryRow(query, buyerId, sellerId).Scan(&exists)
+	return exists, err
+}
+
+func (s *PostgresReviewsStore) CreateReview(r *model.Review) error {
+	query := `
+		INSERT INTO reviews(seller_id, buyer_id, rating, text) VALUES($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+	err := s.DB.QueryRow(query, r.SellerId, r.BuyerId, r.Rating, r.Text).Scan(&r.Id, &r.Date, &r.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrReviewAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *PostgresReviewsStore) GetReviewById(id int64) (*model.Review, error) {
+	r := &model.Review{}
+	if err := scanReview(s.DB.QueryRow(reviewColumns+`WHERE r.id = $1`, id), r); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *PostgresReviewsStore) UpdateReview(r *model.Review) error {
+	query := `UPDATE reviews SET rating = $1, text = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING updated_at`
+	err := s.DB.QueryRow(query, r.Rating, r.Text, r.Id).Scan(&r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrReviewNotFound
+	}
+	return err
+}
+
+func (s *PostgresReviewsStore) ReplyToReview(id int64, reply string) error {
+	query := `UPDATE reviews SET reply = $1, replied_at = CURRENT_TIMESTAMP WHERE id = $2 AND reply IS NULL`
+	res, err := s.DB.Exec(query, reply, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrReviewAlreadyReplied
+	}
+	return nil
+}
+
+func (s *PostgresReviewsStore) GetReviewsBySeller(sellerId int64, page, limit int) ([]model.Review, error) {
+	query := reviewColumns + `
+		WHERE r.seller_id = $1
+		ORDER BY r.created_at DESC
+		LIMIT $2
+		OFFSET $3
+	`
+	offset := (page - 1) * limit
+
+	rows, err := s.DB.Query(query, sellerId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []model.Review{}
+	for rows.Next() {
+		var r model.Review
+		if err := scanReview(rows, &r); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
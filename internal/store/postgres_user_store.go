@@ -10,6 +10,8 @@ import (
 
 var ErrUserAlreadyExists = errors.New("user already exists")
 var ErrInvalidUsernameOrPassword = errors.New("invalid username or password")
+var ErrUserNotFound = errors.New("user not found")
+var ErrUserBanned = errors.New("user is banned")
 
 type PostgresUserStore struct {
 	DB *sql.DB
@@ -20,9 +22,9 @@ func NewPostgresUserStore(db *sql.DB) *PostgresUserStore {
 }
 
 func (s *PostgresUserStore) CreateUser(user *model.User) (int64, error) {
-	query := `INSERT INTO users(username, password) VALUES($1, $2) RETURNING id`
+	query := `INSERT INTO users(username, password, is_admin) VALUES($1, $2, $3) RETURNING id`
 	var id int64
-	err := s.DB.QueryRow(query, user.Username, user.Password).Scan(&id)
+	err := s.DB.QueryRow(query, user.Username, user.Password, user.IsAdmin).Scan(&id)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			return 0, ErrUserAlreadyExists
@@ -32,15 +34,164 @@ func (s *PostgresUserStore) CreateUser(user *model.User) (int64, error) {
 	return id, nil
 }
 
+// GetUserByCredentials returns ErrUserBanned for the right credentials of a
+// banned user.
 func (s *PostgresUserStore) GetUserByCredentials(username, password string) (*model.User, error) {
-	query := `SELECT id, username, password FROM users WHERE username = $1 and password = $2`
+	query := `SELECT id, username, password, is_admin, banned_at FROM users WHERE username = $1 and password = $2`
 	user := &model.User{}
-	err := s.DB.QueryRow(query, username, password).Scan(&user.ID, &user.Username, &user.Password)
+	err := s.DB.QueryRow(query, username, password).Scan(&user.ID, &user.Username, &user.Password, &user.IsAdmin, &user.BannedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrInvalidUsernameOrPassword
 		}
 		return nil, err
 	}
+	if user.BannedAt != nil {
+		return nil, ErrUserBanned
+	}
 	return user, nil
 }
+
+const profileColumns = `
+	SELECT username, COALESCE(display_name, ''), COALESCE(avatar_url, ''), created_at,
+	(SELECT COUNT(*) FROM announcements WHERE announcements.user_id = users.id AND announcements.status = 'active'),
+	(SELECT ROUND(AVG(rating), 2)::float8 FROM reviews WHERE reviews.seller_id = users.id),
+	(SELECT COUNT(*) FROM reviews WHERE reviews.seller_id = users.id)
+	FROM users
+`
+
+func (s *PostgresUserStore) getProfile(query string, args ...any) (*model.UserProfile, error) {
+	profile := &model.UserProfile{}
+	err := s.DB.QueryRow(query, args...).Scan(
+		&profile.Username,
+		&profile.DisplayName,
+		&profile.AvatarURL,
+		&profile.Date,
+		&profile.ActiveAnnouncements,
+		&profile.Rating,
+		&profile.ReviewsCount,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *PostgresUserStore) GetUserIdByUsername(username string) (int64, error) {
+	var id int64
+	err := s.DB.QueryRow(`SELECT id FROM users WHERE username = $1`, username).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	return id, err
+}
+
+func (s *PostgresUserStore) GetProfileByUsername(username string) (*model.UserProfile, error) {
+	return s.getProfile(profileColumns+`WHERE username = $1`, username)
+}
+
+func (s *PostgresUserStore) GetProfileById(id int64) (*model.UserProfile, error) {
+	return s.getProfile(profileColumns+`WHERE id = $1`, id)
+}
+
+// UpdateProfile changes only the fields that are not nil. An empty string clears the field.
+func (s *PostgresUserStore) UpdateProfile(id int64, displayName, avatarURL *string) error {
+	query := `
+		UPDATE users SET
+		display_name = CASE WHEN $2 THEN NULLIF($3, '') ELSE display_name END,
+		avatar_url = CASE WHEN $4 THEN NULLIF($5, '') ELSE avatar_url END
+		WHERE id = $1
+	`
+
+	var displayNameValue, avatarURLValue string
+	if displayName != nil {
+		displayNameValue = *displayName
+	}
+	if avatarURL != nil {
+		avatarURLValue = *avatarURL
+	}
+
+	res, err := s.DB.Exec(query, id, displayName != nil, displayNameValue, avatarURL != nil, avatarURLValue)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListUsers returns a page of the users in the order of registration.
+func (s *PostgresUserStore) ListUsers(page, limit int) ([]model.User, error) {
+	query := `
+		SELECT id, username, COALESCE(display_name, ''), COALESCE(avatar_url, ''), created_at, is_admin, banned_at
+		FROM users ORDER BY id LIMIT $1 OFFSET $2
+	`
+	rows, err := s.DB.Query(query, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var user model.User
+		err := rows.Scan(&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL, &user.Date, &user.IsAdmin, &user.BannedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *PostgresUserStore) UpdatePassword(username, password string) error {
+	res, err := s.DB.Exec(`UPDATE users SET password = $2 WHERE username = $1`, username, password)
+	return userUpdated(res, err)
+}
+
+// SetBanned bans or unbans the user. Banning a banned user keeps the time of
+// the first ban.
+func (s *PostgresUserStore) SetBanned(username string, banned bool) error {
+	query := `
+		UPDATE users SET banned_at = CASE WHEN $2 THEN COALESCE(banned_at, CURRENT_TIMESTAMP) END
+		WHERE username = $1
+	`
+	res, err := s.DB.Exec(query, username, banned)
+	return userUpdated(res, err)
+}
+
+// IsBanned reports whether the user is banned. It returns ErrUserNotFound
+// when there is no such user.
+func (s *PostgresUserStore) IsBanned(id int64) (bool, error) {
+	var banned bool
+	err := s.DB.QueryRow(`SELECT banned_at IS NOT NULL FROM users WHERE id = $1`, id).Scan(&banned)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrUserNotFound
+	}
+	return banned, err
+}
+
+func userUpdated(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package store
+
+import "time"
+
+// RateLimitStore keeps token buckets. A bucket holds up to capacity tokens and
+// refills completely within period.
+type RateLimitStore interface {
+	// Take takes a token from the bucket of the key if there is one. It
+	// reports whether the token was taken and how many tokens are left.
+	Take(key string, capacity int, period time.Duration) (allowed bool, tokens float64, err error)
+}
@@ -0,0 +1,16 @@
+package store
+
+import "time"
+
+// ResponseCache keeps encoded query results. The in-process implementation is
+// MemoryResponseCache; an external one (for example Redis) lets the instances
+// of the service share the results and their invalidation.
+type ResponseCache interface {
+	// Get returns the value of the key and whether it was found and has not
+	// expired.
+	Get(key string) ([]byte, bool, error)
+	// Set keeps the value for ttl.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Clear forgets all the values.
+	Clear() error
+}
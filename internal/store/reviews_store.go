@@ -0,0 +1,12 @@
+package store
+
+import "marketplace-service/internal/model"
+
+type ReviewsStore interface {
+	HasConversation(buyerId, sellerId int64) (bool, error)
+	CreateReview(r *model.Review) error
+	GetReviewById(id int64) (*model.Review, error)
+	UpdateReview(r *model.Review) error
+	ReplyToReview(id int64, reply string) error
+	GetReviewsBySeller(sellerId int64, page, limit int) ([]model.Review, error)
+}
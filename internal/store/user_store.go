@@ -5,4 +5,12 @@ import "marketplace-service/internal/model"
 type UserStore interface {
 	CreateUser(user *model.User) (int64, error)
 	GetUserByCredentials(username, password string) (*model.User, error)
+	GetUserIdByUsername(username string) (int64, error)
+	GetProfileByUsername(username string) (*model.UserProfile, error)
+	GetProfileById(id int64) (*model.UserProfile, error)
+	UpdateProfile(id int64, displayName, avatarURL *string) error
+	ListUsers(page, limit int) ([]model.User, error)
+	UpdatePassword(username, password string) error
+	SetBanned(username string, banned bool) error
+	IsBanned(id int64) (bool, error)
 }
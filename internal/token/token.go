@@ -14,6 +14,7 @@ type Service struct {
 	signingKey     []byte
 	expirationTime time.Duration
 	logger         logger.Logger
+	banned         func(userID string) (bool, error)
 }
 
 func NewService(secretKey string, expirationTime time.Duration, l logger.Logger) *Service {
@@ -24,14 +25,38 @@ func NewService(secretKey string, expirationTime time.Duration, l logger.Logger)
 	}
 }
 
+// RejectBanned sets the check Banned runs, so that a ban does not wait for
+// the tokens of the user to expire.
+func (s *Service) RejectBanned(check func(userID string) (bool, error)) {
+	s.banned = check
+}
+
+// Banned reports whether the user of a valid token is banned. Without a
+// check set nobody is.
+func (s *Service) Banned(userID string) (bool, error) {
+	if s.banned == nil {
+		return false, nil
+	}
+	return s.banned(userID)
+}
+
 func ExtractToken(r *http.Request) string {
 	tokenSplit := strings.Split(r.Header.Get("Authorization"), " ")
-	if tokenSplit[0] != "Bearer" {
+	if len(tokenSplit) != 2 || tokenSplit[0] != "Bearer" {
 		return ""
 	}
 	return tokenSplit[1]
 }
 
+// ExtractTokenOrQuery also accepts the token from the access_token query
+// parameter, because browsers can not set headers on EventSource and WebSocket requests.
+func ExtractTokenOrQuery(r *http.Request) string {
+	if token := ExtractToken(r); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("access_token")
+}
+
 func (s *Service) GenerateToken(userID string) (string, error) {
 	claims := &jwt.RegisteredClaims{
 		Subject:   userID,
@@ -0,0 +1,103 @@
+package users
+
+import (
+	"errors"
+	"marketplace-service/internal/logger"
+	"marketplace-service/internal/middleware"
+	"marketplace-service/internal/problem"
+	"marketplace-service/internal/response"
+	"marketplace-service/internal/store"
+	"marketplace-service/internal/token"
+	"net/http"
+)
+
+type handler struct {
+	db     store.UserStore
+	logger logger.Logger
+	token  *token.Service
+}
+
+// ProfilePatchRequest changes only the fields that are present. An empty string clears the field.
+type ProfilePatchRequest struct {
+	DisplayName *string `json:"display_name" example:"Иван" validate:"omitempty,max=64"`
+	AvatarURL   *string `json:"avatar_url" example:"http://example.com/images/avatar.jpg" validate:"omitempty,max=255,eq=|url"`
+}
+
+func NewHandler(db store.UserStore, logger logger.Logger, token *token.Service) *handler {
+	return &handler{
+		db:     db,
+		logger: logger,
+		token:  token,
+	}
+}
+
+func (h *handler) RegisterService(mux *http.ServeMux) {
+	loggerMiddleware := middleware.LoggingMiddleware(h.logger)
+
+	mux.Handle("GET /api/v1/users/{username}", loggerMiddleware(http.HandlerFunc(h.getProfile)))
+	mux.Handle("PATCH /api/v1/users/me", loggerMiddleware(middleware.AuthMiddleware(h.token, middleware.BindBody[ProfilePatchRequest](h.updateProfile))))
+}
+
+// GetProfile gets a public user page
+// @Summary      Get user profile
+// @Description  Get the public profile of a user: display name, avatar, registration date, number of active announcements and rating.
+// @Tags         Users
+// @Produce      json
+// @Param        username path      string true "Username"
+// @Success      200      {object}  model.UserProfile
+// @Failure      404 {object}  problem.Problem  "User not found"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/users/{username} [get]
+func (h *handler) getProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.db.GetProfileByUsername(r.PathValue("username"))
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			problem.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, profile)
+}
+
+// UpdateProfile edits the profile of the current user
+// @Summary      Edit own profile
+// @Description  Change the display name and avatar of the authorized user. Omitted fields are left as they are, empty strings clear them.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body ProfilePatchRequest true "Profile fields to change"
+// @Success      200     {object}  model.UserProfile "Updated profile"
+// @Failure      400 {object}  problem.Problem  "Invalid request payload"
+// @Failure      413 {object}  problem.Problem  "Request body is too large"
+// @Failure      401 {object}  problem.Problem  "Unauthorized"
+// @Failure      500 {object}  problem.Problem  "Internal server error"
+// @Router       /api/v1/users/me [patch]
+// @Security     Bearer
+func (h *handler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	ppr := middleware.Body[ProfilePatchRequest](r)
+
+	userId, _ := middleware.GetUserId(r)
+
+	if err := h.db.UpdateProfile(int64(userId), ppr.DisplayName, ppr.AvatarURL); err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := h.db.GetProfileById(int64(userId))
+	if err != nil {
+		h.logger.Info(err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.logger, http.StatusOK, profile)
+}
@@ -0,0 +1,88 @@
+package validation
+
+// messages are the messages of the checks made outside the validator. The
+// params of a message are {0}, {1} and so on.
+var messages = map[string]map[string]string{
+	"en": {
+		"validation_failed": "Validation failed",
+		"invalid_fields":    "The request has invalid fields",
+		"invalid_field":     "{0} is invalid",
+		"invalid_value":     "invalid value",
+		"invalid_json":      "The body must be a single JSON object",
+		"unknown_field":     "is not a known field",
+		"wrong_type":        "must be of type {0}",
+
+		"integer":  "must be an integer",
+		"positive": "must be a positive integer",
+		"number":   "must be a number",
+		"between":  "must be between {0} and {1}",
+		"one_of":   "must be one of {0}",
+		"too_long": "must be at most {0} characters long",
+		"date":     "must be a date like {0}",
+
+		"required_param":  "is required",
+		"too_many_values": "at most {0} values are allowed",
+		"repeated_value":  "value {0} is repeated",
+
+		"not_greater_than": "must not be greater than {0}",
+		"not_less_than":    "must not be less than {0}",
+		"set_together":     "must be set together with {0}",
+		"exclusive":        "cannot be set together with {0}",
+		"required_by":      "is required by {0}",
+
+		"unknown_attribute":        "unknown attribute {0} for category {1}",
+		"attribute_required":       "attribute {0} is required for category {1}",
+		"string":                   "must be a string",
+		"boolean":                  "must be a boolean",
+		"true_or_false":            "must be true or false",
+		"range":                    "must be an object with integer from and to",
+		"range_order":              "from must not be greater than to",
+		"unsupported_type":         "unsupported type {0}",
+		"at_least":                 "must be at least {0}",
+		"at_most":                  "must be at most {0}",
+		"filter_requires_category": "filter {0} requires a category",
+		"filter_repeated":          "filter {0} must be set once",
+		"unknown_filter":           "unknown attribute for category {0}",
+	},
+	"ru": {
+		"validation_failed": "Ошибка валидации",
+		"invalid_fields":    "Запрос содержит неверные поля",
+		"invalid_field":     "{0} имеет неверное значение",
+		"invalid_value":     "неверное значение",
+		"invalid_json":      "Тело запроса должно быть одним JSON-объектом",
+		"unknown_field":     "неизвестное поле",
+		"wrong_type":        "должно иметь тип {0}",
+
+		"integer":  "должно быть целым числом",
+		"positive": "должно быть положительным целым числом",
+		"number":   "должно быть числом",
+		"between":  "должно быть от {0} до {1}",
+		"one_of":   "должно быть одним из значений: {0}",
+		"too_long": "должно быть не длиннее {0} символов",
+		"date":     "должно быть датой в формате {0}",
+
+		"required_param":  "обязательный параметр",
+		"too_many_values": "можно указать не более {0} значений",
+		"repeated_value":  "значение {0} указано дважды",
+
+		"not_greater_than": "не должно быть больше {0}",
+		"not_less_than":    "не должно быть меньше {0}",
+		"set_together":     "задается вместе с {0}",
+		"exclusive":        "нельзя задавать вместе с {0}",
+		"required_by":      "обязателен при {0}",
+
+		"unknown_attribute":        "неизвестная характеристика {0} для категории {1}",
+		"attribute_required":       "характеристика {0} обязательна для категории {1}",
+		"string":                   "должно быть строкой",
+		"boolean":                  "должно быть логическим значением",
+		"true_or_false":            "должно быть true или false",
+		"range":                    "должно быть объектом с целыми from и to",
+		"range_order":              "from не должно быть больше to",
+		"unsupported_type":         "неподдерживаемый тип {0}",
+		"at_least":                 "должно быть не меньше {0}",
+		"at_most":                  "должно быть не больше {0}",
+		"filter_requires_category": "фильтр {0} требует указать категорию",
+		"filter_repeated":          "фильтр {0} должен быть указан один раз",
+		"unknown_filter":           "неизвестная характеристика для категории {0}",
+	},
+}
@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// MaxPrice is the highest price of an announcement in rubles.
+const MaxPrice = 1_000_000_000
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// reservedUsernames stand for the current user in the paths, like
+// /api/v1/users/me, and can not be taken at registration.
+var reservedUsernames = []string{"me", "self", "current"}
+
+// tag is a marketplace check with its messages.
+type tag struct {
+	name     string
+	check    validator.Func
+	messages map[string]string
+}
+
+var tags = []tag{
+	{
+		name:  "username",
+		check: username,
+		messages: map[string]string{
+			"en": "{0} may contain only Latin letters, digits, '_', '.' and '-' and must not be me, self or current",
+			"ru": "{0} может содержать только латинские буквы, цифры, '_', '.' и '-' и не может быть me, self или current",
+		},
+	},
+	{
+		name:  "password",
+		check: strongPassword,
+		messages: map[string]string{
+			"en": "{0} must contain a lowercase letter, an uppercase letter and a digit",
+			"ru": "{0} должен содержать строчную букву, заглавную букву и цифру",
+		},
+	},
+	{
+		name:  "price",
+		check: func(fl validator.FieldLevel) bool { return fl.Field().Int() >= 0 && fl.Field().Int() <= MaxPrice },
+		messages: map[string]string{
+			"en": "{0} must be between 0 and " + strconv.Itoa(MaxPrice),
+			"ru": "{0} должно быть от 0 до " + strconv.Itoa(MaxPrice),
+		},
+	},
+	{
+		name:  "safeurl",
+		check: func(fl validator.FieldLevel) bool { return safeURL(fl.Field().String()) },
+		messages: map[string]string{
+			"en": "{0} must be an http or https link without a login",
+			"ru": "{0} должен быть ссылкой http или https без логина",
+		},
+	},
+}
+
+func username(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	return usernamePattern.MatchString(value) && !slices.Contains(reservedUsernames, strings.ToLower(value))
+}
+
+// strongPassword requires a lowercase letter, an uppercase letter and a digit.
+// The length is checked by min and max.
+func strongPassword(fl validator.FieldLevel) bool {
+	var lower, upper, digit bool
+	for _, r := range fl.Field().String() {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return lower && upper && digit
+}
+
+// safeURL accepts absolute http and https links. Links with credentials are
+// refused as they are used to disguise the real host.
+func safeURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Hostname() != "" && u.User == nil
+}
+
+func registerTags(v *validator.Validate, translators map[string]ut.Translator) {
+	for _, t := range tags {
+		if err := v.RegisterValidation(t.name, t.check); err != nil {
+			panic(err)
+		}
+
+		for lang, trans := range translators {
+			text := t.messages[lang]
+			err := v.RegisterTranslation(t.name, trans,
+				func(trans ut.Translator) error { return trans.Add(t.name, text, true) },
+				func(trans ut.Translator, fe validator.FieldError) string {
+					message, _ := trans.T(fe.Tag(), fe.Field())
+					return message
+				},
+			)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+}
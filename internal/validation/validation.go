@@ -0,0 +1,255 @@
+// Package validation checks the requests and reports the failures in the
+// language of the client. Russian is the default, English is chosen by
+// Accept-Language.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"marketplace-service/internal/problem"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	ru_translations "github.com/go-playground/validator/v10/translations/ru"
+	"golang.org/x/text/language"
+)
+
+var (
+	validate  *validator.Validate
+	universal *ut.UniversalTranslator
+	matcher   = language.NewMatcher([]language.Tag{language.Russian, language.English})
+)
+
+func init() {
+	validate = validator.New()
+	validate.RegisterTagNameFunc(jsonName)
+
+	universal = ut.New(ru.New(), ru.New(), en.New())
+
+	english, _ := universal.GetTranslator("en")
+	russian, _ := universal.GetTranslator("ru")
+
+	if err := en_translations.RegisterDefaultTranslations(validate, english); err != nil {
+		panic(err)
+	}
+	if err := ru_translations.RegisterDefaultTranslations(validate, russian); err != nil {
+		panic(err)
+	}
+
+	registerTags(validate, map[string]ut.Translator{"en": english, "ru": russian})
+
+	for key, text := range messages["en"] {
+		if err := english.Add(messageKey(key), text, false); err != nil {
+			panic(err)
+		}
+	}
+	for key, text := range messages["ru"] {
+		if err := russian.Add(messageKey(key), text, false); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// jsonName names the fields after their JSON keys.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Struct checks the struct against its validate tags.
+func Struct(s any) error {
+	return validate.Struct(s)
+}
+
+// Error is a failed check whose message is translated when it is written.
+// Params that are errors are translated too. Field is set for the checks of
+// a single field of the request.
+type Error struct {
+	Field  string
+	Key    string
+	Params []any
+}
+
+// Errorf makes an error with the message of the key.
+func Errorf(key string, params ...any) error {
+	return &Error{Key: key, Params: params}
+}
+
+// InField makes the error an error of the field.
+func InField(field string, err error) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return &Error{Field: field, Key: "invalid_value"}
+	}
+
+	inField := *e
+	inField.Field = field
+	return &inField
+}
+
+// Errors are the failures of several fields reported together.
+type Errors []error
+
+// Join reports the errors together. It returns nil without errors and the
+// error itself for a single one.
+func Join(errs ...error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return Errors(errs)
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *Error) Error() string {
+	english, _ := universal.GetTranslator("en")
+	if e.Field != "" {
+		return e.Field + ": " + e.translate(english)
+	}
+	return e.translate(english)
+}
+
+func (e *Error) translate(trans ut.Translator) string {
+	params := make([]string, len(e.Params))
+	for i, param := range e.Params {
+		params[i] = translateParam(trans, param)
+	}
+
+	return translate(trans, e.Key, params...)
+}
+
+func translateParam(trans ut.Translator, param any) string {
+	var e *Error
+	if err, ok := param.(error); ok && errors.As(err, &e) {
+		return e.translate(trans)
+	}
+	// Bounds are floats; large ones must not turn into the exponent form.
+	if f, ok := param.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(param)
+}
+
+// Language negotiates the language of the response from Accept-Language.
+func Language(r *http.Request) string {
+	tag, _ := language.MatchStrings(matcher, r.Header.Get("Accept-Language"))
+	base, _ := tag.Base()
+	return base.String()
+}
+
+// Translator is the translator for the language of the request.
+func Translator(r *http.Request) ut.Translator {
+	trans, _ := universal.GetTranslator(Language(r))
+	return trans
+}
+
+// Translate gives the message of the error in the language of the request.
+func Translate(r *http.Request, err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.translate(Translator(r))
+	}
+	return err.Error()
+}
+
+// Problem turns the error into a validation problem in the language of the
+// request. Failures of the validator, errors of a field and Errors become the
+// errors of the fields; any other error is the detail.
+func Problem(r *http.Request, err error) *problem.Problem {
+	trans := Translator(r)
+	p := &problem.Problem{
+		Type:   problem.TypeValidation,
+		Title:  translate(trans, "validation_failed"),
+		Status: http.StatusBadRequest,
+	}
+
+	var list Errors
+	var fields validator.ValidationErrors
+	var e *Error
+	switch {
+	case errors.As(err, &list):
+		p.Detail = translate(trans, "invalid_fields")
+		for _, err := range list {
+			p.Errors = append(p.Errors, fieldErrors(trans, err)...)
+		}
+
+	case errors.As(err, &fields), errors.As(err, &e) && e.Field != "":
+		p.Detail = translate(trans, "invalid_fields")
+		p.Errors = fieldErrors(trans, err)
+
+	default:
+		p.Detail = Translate(r, err)
+	}
+
+	return p
+}
+
+// fieldErrors translates one of the errors reported together.
+func fieldErrors(trans ut.Translator, err error) []problem.FieldError {
+	var fields validator.ValidationErrors
+	var e *Error
+	switch {
+	case errors.As(err, &fields):
+		list := make([]problem.FieldError, len(fields))
+		for i, fe := range fields {
+			list[i] = problem.FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: message(trans, fe)}
+		}
+		return list
+	case errors.As(err, &e):
+		return []problem.FieldError{{Field: e.Field, Rule: e.Key, Message: e.translate(trans)}}
+	}
+	return []problem.FieldError{{Rule: "invalid_value", Message: translate(trans, "invalid_value")}}
+}
+
+// WriteError replies with a validation problem in the language of the request.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Content-Language", Language(r))
+	w.Header().Add("Vary", "Accept-Language")
+	problem.Write(w, Problem(r, err))
+}
+
+// message translates a failure of the validator. Tags without a translation
+// get a general message instead of the English text of the validator.
+func message(trans ut.Translator, fe validator.FieldError) string {
+	text := fe.Translate(trans)
+	if text == fe.Error() {
+		return translate(trans, "invalid_field", fe.Field())
+	}
+	return text
+}
+
+// messageKey keeps the keys of the messages apart from the tags of the
+// validator, which share the translator.
+func messageKey(key string) string {
+	return "message." + key
+}
+
+func translate(trans ut.Translator, key string, params ...string) string {
+	text, err := trans.T(messageKey(key), params...)
+	if err != nil {
+		return key
+	}
+	return text
+}